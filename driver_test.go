@@ -0,0 +1,228 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// fake driver to test the wrappers without a database
+// each statement is recorded and answered by fakeDB.result
+
+func init() {
+	sql.Register("sqlofake", fakeDriver{})
+}
+
+var fakeDBs sync.Map // dsn -> *fakeDB
+
+type fakeRes struct {
+	cols     []string
+	types    []string // DatabaseTypeName of cols
+	rows     [][]driver.Value
+	affected int64
+	err      error
+	block    bool // wait for the end of ctx
+}
+
+type fakeDB struct {
+	mu      sync.Mutex
+	queries []string
+	args    [][]any
+	result  func(query string, args []any) fakeRes
+
+	prepared []string // by Prepare
+	closed   int      // statements closed
+}
+
+func (f *fakeDB) record(query string, args []driver.NamedValue) fakeRes {
+	vals := make([]any, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	f.mu.Lock()
+	f.queries = append(f.queries, query)
+	f.args = append(f.args, vals)
+	result := f.result
+	f.mu.Unlock()
+	if result == nil {
+		return fakeRes{}
+	}
+	return result(query, vals)
+}
+
+func (f *fakeDB) Queries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.queries...)
+}
+
+func openFake(t *testing.T) (*sqlx.DB, *fakeDB) {
+	t.Helper()
+	f := &fakeDB{}
+	dsn := t.Name()
+	fakeDBs.Store(dsn, f)
+	db, err := sqlx.Open("sqlofake", dsn)
+	if err != nil {
+		t.Fatalf("open fake: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeDBs.Delete(dsn)
+	})
+	return db, f
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	f, ok := fakeDBs.Load(dsn)
+	if !ok {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return &fakeConn{db: f.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	c.db.prepared = append(c.db.prepared, query)
+	c.db.mu.Unlock()
+	return &fakeStmt{c: c, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	q := "BEGIN"
+	if opts.Isolation != 0 {
+		q += " " + sql.IsolationLevel(opts.Isolation).String()
+	}
+	if opts.ReadOnly {
+		q += " READ ONLY"
+	}
+	res := c.db.record(q, nil)
+	if res.err != nil {
+		return nil, res.err
+	}
+	return &fakeTx{c: c}, nil
+}
+
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	res := c.db.record(query, args)
+	if res.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	return driver.RowsAffected(res.affected), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	res := c.db.record(query, args)
+	if res.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	return &fakeRows{cols: res.cols, types: res.types, rows: res.rows}, nil
+}
+
+type fakeTx struct {
+	c *fakeConn
+}
+
+func (t *fakeTx) Commit() error   { return t.c.db.record("COMMIT", nil).err }
+func (t *fakeTx) Rollback() error { return t.c.db.record("ROLLBACK", nil).err }
+
+type fakeStmt struct {
+	c     *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	s.c.db.mu.Lock()
+	s.c.db.closed++
+	s.c.db.mu.Unlock()
+	return nil
+}
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	return nv
+}
+
+type fakeRows struct {
+	cols  []string
+	types []string
+	rows  [][]driver.Value
+	i     int
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string {
+	if i < len(r.types) {
+		return r.types[i]
+	}
+	return ""
+}
+func (r *fakeRows) Close() error { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
+
+// oneRow answer a single row with a single column to every query
+func oneRow(col string, v driver.Value) func(string, []any) fakeRes {
+	return func(query string, args []any) fakeRes {
+		if strings.HasPrefix(query, "BEGIN") || query == "COMMIT" || query == "ROLLBACK" {
+			return fakeRes{}
+		}
+		return fakeRes{cols: []string{col}, rows: [][]driver.Value{{v}}, affected: 1}
+	}
+}
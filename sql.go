@@ -5,6 +5,7 @@ package sqlo
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 
 	"github.com/jmoiron/sqlx"
@@ -13,13 +14,17 @@ import (
 type Selecter interface {
 	Select(any, string, ...any) error
 	Get(any, string, ...any) error
+	Query(string, ...any) (*sqlx.Rows, error)
+	SelectMaps(string, ...any) ([]map[string]any, error)
+	GetMap(string, ...any) (map[string]any, error)
+	SelectTable(string, ...any) (*Table, error)
 }
 
 type Execer interface {
 	Selecter
 	Exec(string, ...any) (sql.Result, error)
 	MustExec(string, ...any) sql.Result
-	//	NamedExec(string, any) (sql.Result, error)
+	NamedExec(string, any) (sql.Result, error)
 	InsertMap(string, map[string]any) (sql.Result, error)
 	InsertMapReturning(any, string, string, map[string]any) error
 	UpdateMap(string, map[string]any, string, ...any) (sql.Result, error)
@@ -43,14 +48,54 @@ func (x *Sx) log(query string, args ...any) {
 	x.Logger.Println(sql_fake(x.DbType, query, args...))
 }
 
+// queryErr wrap err in a *QueryError like DB Tx Conn
+func (x *Sx) queryErr(op string, query string, args []any, err error) error {
+	if err == nil {
+		return nil
+	}
+	return env{dbType: x.DbType}.queryErr(op, query, args, err)
+}
+
 func (x *Sx) Select(dest any, query string, args ...any) error {
 	x.log(query, args...)
-	return sqlx.Select(x.Sx, dest, query, args...)
+	return x.queryErr("Select", query, args, sqlx.Select(x.Sx, dest, query, args...))
 }
 
 func (x *Sx) Get(dest any, query string, args ...any) error {
 	x.log(query, args...)
-	return sqlx.Get(x.Sx, dest, query, args...)
+	return x.queryErr("Get", query, args, sqlx.Get(x.Sx, dest, query, args...))
+}
+
+func (x *Sx) Query(query string, args ...any) (*sqlx.Rows, error) {
+	x.log(query, args...)
+	rows, err := x.Sx.Queryx(query, args...)
+	return rows, x.queryErr("Query", query, args, err)
+}
+
+// SelectMaps renvoi les lignes en map, for columns unknown before
+// the []byte of text columns are converted to string
+func (x *Sx) SelectMaps(query string, args ...any) ([]map[string]any, error) {
+	return selectMaps(x, query, args...)
+}
+
+// GetMap renvoi la première ligne en map, sql.ErrNoRows if none
+func (x *Sx) GetMap(query string, args ...any) (map[string]any, error) {
+	return getMap(x, query, args...)
+}
+
+// SelectTable is like SelectMaps but keep the order and the type of the columns
+func (x *Sx) SelectTable(query string, args ...any) (*Table, error) {
+	return selectTable(x, query, args...)
+}
+
+// Exists tell if table has a row matching w (can be nil)
+func (x *Sx) Exists(table string, w *Where) (bool, error) {
+	return exists(x, table, w)
+}
+
+// Count renvoi le nombre de lignes de table matching w (can be nil)
+func (x *Sx) Count(table string, w *Where) (int64, error) {
+	return count(x, table, w)
 }
 
 func (x *Sx) MustExec(query string, args ...any) sql.Result {
@@ -63,16 +108,24 @@ func (x *Sx) MustExec(query string, args ...any) sql.Result {
 
 func (x *Sx) Exec(query string, args ...any) (sql.Result, error) {
 	x.log(query, args...)
-	return x.Sx.Exec(query, args...)
+	res, err := x.Sx.Exec(query, args...)
+	return res, x.queryErr("Exec", query, args, err)
 }
 
+// NamedExec take the placeholders of the driver, like sqlx.NamedExec
 func (x *Sx) NamedExec(query string, arg any) (sql.Result, error) {
-	x.log(query, arg)
-	return sqlx.NamedExec(x.Sx, query, arg)
+	s, args, err := sqlx.BindNamed(sqlx.BindType(x.Sx.DriverName()), query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("NamedExec: %w", err)
+	}
+	return x.Exec(s, args...)
 }
 
 func (x *Sx) InsertMap(table string, m map[string]any) (sql.Result, error) {
-	s, values := insertSt(x.DbType, table, m)
+	s, values, err := insertSt(x.DbType, table, m)
+	if err != nil {
+		return nil, fmt.Errorf("InsertMap: %w", err)
+	}
 	res, err := x.Exec(s, values...)
 	return res, err
 }
@@ -82,13 +135,19 @@ func (x *Sx) InsertMap(table string, m map[string]any) (sql.Result, error) {
 // dest must be a pointer to destination
 // returning is the name(s) of the field(s)
 func (x *Sx) InsertMapReturning(dest any, returning string, table string, m map[string]any) error {
-	s, values := insertSt(x.DbType, table, m)
+	s, values, err := insertSt(x.DbType, table, m)
+	if err != nil {
+		return fmt.Errorf("InsertMapReturning: %w", err)
+	}
 	s += " returning " + returning
 	return x.Get(dest, s, values...)
 }
 
 func (x *Sx) UpdateMap(table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
-	s, values := updateSt(x.DbType, table, m, where, where_vals...)
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
 	res, err := x.Exec(s, values...)
 
 	return res, err
@@ -99,7 +158,10 @@ func (x *Sx) UpdateMap(table string, m map[string]any, where string, where_vals
 // dest must be a pointer to destination
 // returning is the name(s) of the field(s)
 func (x *Sx) UpdateMapReturning(dest any, returning string, table string, m map[string]any, where string, where_vals ...any) error {
-	s, values := updateSt(x.DbType, table, m, where, where_vals...)
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
 	s += " returning " + returning
 	return x.Get(dest, s, values...)
 }
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrRowsAffected is returned by UpdateMapOne UpdateMapExpect DeleteOne DeleteExpect
+// when the statement did not affect the expected number of rows
+// in a Tx the statement is done, it's up to the caller to rollback
+type ErrRowsAffected struct {
+	Expected int64
+	Got      int64
+}
+
+func (e ErrRowsAffected) Error() string {
+	return fmt.Sprintf("rows affected: expected %d got %d", e.Expected, e.Got)
+}
+
+// expectRows check that res affected n rows
+func expectRows(n int64, res sql.Result, err error) (sql.Result, error) {
+	if err != nil {
+		return res, err
+	}
+	got, err := res.RowsAffected()
+	if err != nil {
+		return res, err
+	}
+	if got != n {
+		return res, ErrRowsAffected{Expected: n, Got: got}
+	}
+	return res, nil
+}
+
+// ErrUnsafeWhere is returned with SafeUpdates by UpdateMap and Delete
+// when the where is empty or always true
+var ErrUnsafeWhere = errors.New("unsafe where")
+
+var unsafe_re_eq = regexp.MustCompile(`^([^=<>!]+)=([^=<>!]+)$`)
+var unsafe_re_num = regexp.MustCompile(`^[0-9.]+$`)
+
+// checkWhere refuse, with safe, a where empty, true, a number
+// or a comparison of the same terms (1=1, 'a'='a', id=id)
+func checkWhere(safe bool, where string) error {
+	if !safe {
+		return nil
+	}
+	s := strings.ToLower(strings.Join(strings.Fields(where), ""))
+	for strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+	}
+	unsafe := s == "" || s == "true" || (unsafe_re_num.MatchString(s) && strings.Trim(s, "0.") != "")
+	if m := unsafe_re_eq.FindStringSubmatch(s); m != nil && m[1] == m[2] {
+		unsafe = true
+	}
+	if unsafe {
+		return fmt.Errorf("%w %q", ErrUnsafeWhere, where)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckWhere(t *testing.T) {
+	tbl := []struct {
+		where string
+		ok    bool
+	}{
+		{"id=$1", true},
+		{"a=b", true},
+		{"0", true},
+		{"", false},
+		{"  ", false},
+		{"true", false},
+		{"TRUE", false},
+		{"1", false},
+		{"1=1", false},
+		{" ( 1 = 1 ) ", false},
+		{"'a'='a'", false},
+		{"id=id", false},
+	}
+	for _, tt := range tbl {
+		err := checkWhere(true, tt.where)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q attend %v reçoit %v", tt.where, tt.ok, err)
+		}
+		if err != nil && !errors.Is(err, ErrUnsafeWhere) {
+			t.Errorf("%q attend ErrUnsafeWhere reçoit %v", tt.where, err)
+		}
+	}
+	if err := checkWhere(false, "1=1"); err != nil {
+		t.Errorf("sans SafeUpdates: %v", err)
+	}
+}
+
+func TestRowsAffected(t *testing.T) {
+	db, f := openFake(t)
+	var affected int64 = 2
+	f.result = func(q string, _ []any) fakeRes { return fakeRes{affected: affected} }
+	x := WrapDB(context.Background(), db)
+
+	_, err := x.UpdateMapOne("t", map[string]any{"a": 1}, "id=$1", 1)
+	var ra ErrRowsAffected
+	if !errors.As(err, &ra) || ra.Expected != 1 || ra.Got != 2 {
+		t.Errorf("attend ErrRowsAffected reçoit %v", err)
+	}
+	if _, err := x.UpdateMapExpect(2, "t", map[string]any{"a": 1}, "id>$1", 1); err != nil {
+		t.Error(err)
+	}
+	if _, err := x.DeleteExpect(2, "user", "id>$1", 1); err != nil {
+		t.Error(err)
+	}
+	if q := f.Queries()[len(f.Queries())-1]; q != `DELETE FROM "user" WHERE id>$1` {
+		t.Errorf("delete %s", q)
+	}
+
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	affected = 0
+	_, err = tx.DeleteOne("t", "id=$1", 1)
+	if !errors.As(err, &ra) || ra.Got != 0 {
+		t.Errorf("attend ErrRowsAffected reçoit %v", err)
+	}
+	if tx.State() != TxActive {
+		t.Errorf("la Tx est laissée à l'appelant %v", tx.State())
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSafeUpdates(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.SafeUpdates = true
+	if _, err := x.UpdateMap("t", map[string]any{"a": 1}, "1=1"); !errors.Is(err, ErrUnsafeWhere) {
+		t.Errorf("UpdateMap attend ErrUnsafeWhere reçoit %v", err)
+	}
+	if _, err := x.Delete("t", ""); !errors.Is(err, ErrUnsafeWhere) {
+		t.Errorf("Delete attend ErrUnsafeWhere reçoit %v", err)
+	}
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Delete("t", "true"); !errors.Is(err, ErrUnsafeWhere) {
+		t.Errorf("Tx Delete attend ErrUnsafeWhere reçoit %v", err)
+	}
+	for _, q := range f.Queries() {
+		if !strings.HasPrefix(q, "BEGIN") {
+			t.Errorf("requête envoyée %s", q)
+		}
+	}
+	if _, err := x.Delete("t", "id=$1", 1); err != nil {
+		t.Error(err)
+	}
+}
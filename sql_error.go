@@ -0,0 +1,132 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// DBError is the error of the driver with the details it gives
+// the errors of DB Tx Conn unwrap to it when it's an error of the database
+type DBError struct {
+	Code       string // sqlstate with postgres, error number with sqlserver
+	Message    string
+	Constraint string // postgres only
+	Table      string // postgres only
+	Column     string // postgres only
+	Err        error  // of the driver
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// mssqlError is implemented by the errors of github.com/microsoft/go-mssqldb
+type mssqlError interface {
+	SQLErrorNumber() int32
+}
+
+// asDBError renvoi le DBError de err, even if err is not wrapped
+func asDBError(err error) (*DBError, bool) {
+	if err == nil {
+		return nil, false
+	}
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return dbErr, true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return &DBError{
+			Code:       string(pqErr.Code),
+			Message:    pqErr.Message,
+			Constraint: pqErr.Constraint,
+			Table:      pqErr.Table,
+			Column:     pqErr.Column,
+			Err:        err,
+		}, true
+	}
+	var msErr mssqlError
+	if errors.As(err, &msErr) {
+		dbErr := &DBError{
+			Code: strconv.Itoa(int(msErr.SQLErrorNumber())),
+			Err:  err,
+		}
+		if m, ok := msErr.(interface{ SQLErrorMessage() string }); ok {
+			dbErr.Message = m.SQLErrorMessage()
+		}
+		return dbErr, true
+	}
+	return nil, false
+}
+
+// wrapDBError wrap an error of the database in a *DBError
+func wrapDBError(err error) error {
+	if dbErr, ok := asDBError(err); ok {
+		return dbErr
+	}
+	return err
+}
+
+func hasCode(err error, codes ...string) bool {
+	dbErr, ok := asDBError(err)
+	if !ok {
+		return false
+	}
+	for _, c := range codes {
+		if dbErr.Code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlserver use 547 for foreign key and check constraints
+func mssqlConstraint(err error, kind string) bool {
+	dbErr, ok := asDBError(err)
+	return ok && dbErr.Code == "547" && strings.Contains(dbErr.Message+dbErr.Err.Error(), kind)
+}
+
+// IsUniqueViolation: postgres 23505, sqlserver 2627 2601
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, "23505", "2627", "2601")
+}
+
+// IsForeignKeyViolation: postgres 23503, sqlserver 547 FOREIGN KEY or REFERENCE
+func IsForeignKeyViolation(err error) bool {
+	return hasCode(err, "23503") || mssqlConstraint(err, "FOREIGN KEY") || mssqlConstraint(err, "REFERENCE")
+}
+
+// IsNotNullViolation: postgres 23502, sqlserver 515
+func IsNotNullViolation(err error) bool {
+	return hasCode(err, "23502", "515")
+}
+
+// IsCheckViolation: postgres 23514, sqlserver 547 CHECK
+func IsCheckViolation(err error) bool {
+	return hasCode(err, "23514") || mssqlConstraint(err, "CHECK")
+}
+
+// IsDeadlock: postgres 40P01, sqlserver 1205
+func IsDeadlock(err error) bool {
+	return hasCode(err, "40P01", "1205")
+}
+
+// IsSerializationFailure: postgres 40001, sqlserver 3960 (snapshot update conflict)
+func IsSerializationFailure(err error) bool {
+	return hasCode(err, "40001", "3960")
+}
+
+// IsNotFound: sql.ErrNoRows
+func IsNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
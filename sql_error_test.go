@@ -0,0 +1,82 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+type msErrorMsg struct {
+	n   int32
+	msg string
+}
+
+func (e msErrorMsg) Error() string           { return "mssql: " + e.msg }
+func (e msErrorMsg) SQLErrorNumber() int32   { return e.n }
+func (e msErrorMsg) SQLErrorMessage() string { return e.msg }
+
+func TestIsErrors(t *testing.T) {
+	type is func(error) bool
+	tbl := []struct {
+		name string
+		f    is
+		err  error
+		ok   bool
+	}{
+		{"unique pg", IsUniqueViolation, &pq.Error{Code: "23505"}, true},
+		{"unique ms", IsUniqueViolation, msError{2627}, true},
+		{"unique wrapped", IsUniqueViolation, fmt.Errorf("x: %w", &pq.Error{Code: "23505"}), true},
+		{"unique fk", IsUniqueViolation, &pq.Error{Code: "23503"}, false},
+		{"fk pg", IsForeignKeyViolation, &pq.Error{Code: "23503"}, true},
+		{"fk ms", IsForeignKeyViolation, msErrorMsg{547, "conflicted with the FOREIGN KEY constraint"}, true},
+		{"fk ms check", IsForeignKeyViolation, msErrorMsg{547, "conflicted with the CHECK constraint"}, false},
+		{"check ms", IsCheckViolation, msErrorMsg{547, "conflicted with the CHECK constraint"}, true},
+		{"check pg", IsCheckViolation, &pq.Error{Code: "23514"}, true},
+		{"not null pg", IsNotNullViolation, &pq.Error{Code: "23502"}, true},
+		{"not null ms", IsNotNullViolation, msError{515}, true},
+		{"deadlock pg", IsDeadlock, &pq.Error{Code: "40P01"}, true},
+		{"deadlock ms", IsDeadlock, msError{1205}, true},
+		{"serialization pg", IsSerializationFailure, &pq.Error{Code: "40001"}, true},
+		{"not found", IsNotFound, fmt.Errorf("x: %w", sql.ErrNoRows), true},
+		{"nil", IsUniqueViolation, nil, false},
+		{"other", IsDeadlock, errors.New("x"), false},
+	}
+	for _, s := range tbl {
+		if s.f(s.err) != s.ok {
+			t.Errorf("%s: %v attend %v", s.name, s.err, s.ok)
+		}
+	}
+}
+
+func TestDBError(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	pqErr := &pq.Error{Code: "23505", Message: "duplicate", Constraint: "t_pkey", Table: "t", Column: "id"}
+	f.result = func(string, []any) fakeRes { return fakeRes{err: pqErr} }
+
+	_, err := x.InsertMap("t", map[string]any{"id": 1})
+	var dbErr *DBError
+	if !errors.As(err, &dbErr) {
+		t.Fatalf("attend DBError reçoit %T %v", err, err)
+	}
+	if dbErr.Code != "23505" || dbErr.Constraint != "t_pkey" || dbErr.Table != "t" || dbErr.Column != "id" {
+		t.Errorf("DBError %+v", dbErr)
+	}
+	var e *pq.Error
+	if !errors.As(err, &e) || !IsUniqueViolation(err) {
+		t.Errorf("doit unwrap vers pq.Error %v", err)
+	}
+
+	f.result = func(string, []any) fakeRes { return fakeRes{err: msErrorMsg{2601, "duplicate key"}} }
+	err = x.Get(new(int), "select 1")
+	if !errors.As(err, &dbErr) || dbErr.Code != "2601" || dbErr.Message != "duplicate key" {
+		t.Errorf("DBError mssql %v", err)
+	}
+}
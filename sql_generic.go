@@ -0,0 +1,33 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Select renvoi les lignes de la requête dans un []T
+// x can be a Sx, DB, Tx or Conn
+func Select[T any](x Selecter, query string, args ...any) ([]T, error) {
+	var dest []T
+	err := x.Select(&dest, query, args...)
+	return dest, err
+}
+
+// Get renvoi la ligne de la requête dans un T
+// sql.ErrNoRows if there is no row
+func Get[T any](x Selecter, query string, args ...any) (T, error) {
+	var dest T
+	err := x.Get(&dest, query, args...)
+	return dest, err
+}
+
+// GetOpt is like Get but renvoi false instead of sql.ErrNoRows
+func GetOpt[T any](x Selecter, query string, args ...any) (T, bool, error) {
+	dest, err := Get[T](x, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dest, false, nil
+	}
+	return dest, err == nil, err
+}
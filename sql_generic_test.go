@@ -0,0 +1,46 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+var _ Selecter = (*Sx)(nil)
+var _ Selecter = (*DB)(nil)
+var _ Selecter = (*Tx)(nil)
+var _ Selecter = (*Conn)(nil)
+
+func TestGeneric(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	type Row struct {
+		ID   int64
+		Name string
+	}
+	f.result = func(string, []any) fakeRes {
+		return fakeRes{cols: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}}
+	}
+	rows, err := Select[Row](x, "select id, name from t")
+	if err != nil || len(rows) != 2 || rows[1] != (Row{2, "b"}) {
+		t.Errorf("Select %v %v", rows, err)
+	}
+	row, err := Get[Row](x, "select id, name from t")
+	if err != nil || row != (Row{1, "a"}) {
+		t.Errorf("Get %v %v", row, err)
+	}
+
+	f.result = func(string, []any) fakeRes { return fakeRes{cols: []string{"n"}} }
+	n, ok, err := GetOpt[int](x, "select n from t")
+	if n != 0 || ok || err != nil {
+		t.Errorf("GetOpt %v %v %v", n, ok, err)
+	}
+	if _, err := Get[int](x, "select n from t"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Get attend ErrNoRows reçoit %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidIdent is returned by InsertMap UpdateMap Exists Pluck... for a table
+// or a column which is not a plain identifier
+var ErrInvalidIdent = errors.New("invalid identifier")
+
+var ident_re = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reserved words quoted by QuoteIdent
+var reservedWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		all alter and any as asc authorization between both by case cast check
+		collate column constraint create cross current_date current_time
+		current_timestamp current_user default delete desc distinct do drop
+		else end except exists fetch for foreign from full grant group having
+		in index inner insert intersect into is join key leading left like limit
+		not null offset on only or order outer percent primary references right
+		select session_user set some table then to top trailing union unique
+		update user using values view when where with`) {
+		reservedWords[w] = true
+	}
+}
+
+// QuoteIdent valide name, a table or a column, schema.table is allowed
+// each part must be a letter or _ followed by letters, digits or _
+// or already quoted ("x" with postgres, [x] with sqlserver and access)
+// the reserved words (user, order...) are quoted
+// "x" with postgres, [x] with sqlserver and access
+// (`x` of mysql is not used, there is no DbType for it)
+func QuoteIdent(dbType int, name string) (string, error) {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		switch {
+		case isQuotedIdent(dbType, p):
+		case !ident_re.MatchString(p):
+			return "", fmt.Errorf("%w %q", ErrInvalidIdent, name)
+		case reservedWords[strings.ToLower(p)]:
+			if dbType == DB_PG {
+				parts[i] = `"` + p + `"`
+			} else {
+				parts[i] = "[" + p + "]"
+			}
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// isQuotedIdent tell if p is already quoted for dbType
+func isQuotedIdent(dbType int, p string) bool {
+	open, close := `"`, `"`
+	if dbType != DB_PG {
+		open, close = "[", "]"
+	}
+	if len(p) < 3 || !strings.HasPrefix(p, open) || !strings.HasSuffix(p, close) {
+		return false
+	}
+	return !strings.ContainsAny(p[1:len(p)-1], `"[]`)
+}
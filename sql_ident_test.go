@@ -0,0 +1,69 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	tbl := []struct {
+		dbType int
+		name   string
+		want   string
+		ok     bool
+	}{
+		{DB_PG, "mytable", "mytable", true},
+		{DB_PG, "public.mytable", "public.mytable", true},
+		{DB_PG, "user", `"user"`, true},
+		{DB_PG, "public.Order", `public."Order"`, true},
+		{DB_MSSQL, "dbo.user", "dbo.[user]", true},
+		{DB_ACCESS, "order", "[order]", true},
+		{DB_PG, `"My Col"`, `"My Col"`, true},
+		{DB_MSSQL, "[My Col]", "[My Col]", true},
+		{DB_MSSQL, `"My Col"`, "", false},
+		{DB_PG, "a; drop table t", "", false},
+		{DB_PG, "a=1,b", "", false},
+		{DB_PG, "1a", "", false},
+		{DB_PG, "a..b", "", false},
+		{DB_PG, "", "", false},
+		{DB_PG, `"a"";drop"`, "", false},
+	}
+	for _, tt := range tbl {
+		got, err := QuoteIdent(tt.dbType, tt.name)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("%q attend %q %v reçoit %q %v", tt.name, tt.want, tt.ok, got, err)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidIdent) {
+			t.Errorf("%q attend ErrInvalidIdent reçoit %v", tt.name, err)
+		}
+	}
+}
+
+func TestInsertMapIdent(t *testing.T) {
+	q, _, err := insertSt(DB_PG, "public.user", map[string]any{"order": 1, "name": "a"})
+	if err != nil || q != `INSERT INTO public."user" (name, "order") VALUES ($1, $2)` {
+		t.Errorf("%s %v", q, err)
+	}
+	q, _, err = updateSt(DB_MSSQL, "user", map[string]any{"order": 1}, "id=@p1", 1)
+	if err != nil || q != "UPDATE [user] SET [order]=@p2 WHERE id=@p1" {
+		t.Errorf("%s %v", q, err)
+	}
+
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	_, err = x.InsertMap("t", map[string]any{"a) values (1); drop table t; --": 1})
+	if !errors.Is(err, ErrInvalidIdent) {
+		t.Errorf("attend ErrInvalidIdent reçoit %v", err)
+	}
+	_, err = x.UpdateMap("t t2", map[string]any{"a": 1}, "id=$1", 1)
+	if !errors.Is(err, ErrInvalidIdent) {
+		t.Errorf("attend ErrInvalidIdent reçoit %v", err)
+	}
+	if len(f.Queries()) != 0 {
+		t.Errorf("requêtes envoyées %v", f.Queries())
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LeakDetector record the Conn (from DB.Conn) and Tx of a DB
+// until they are closed, committed or rolled back
+// set it to DB.Leaks
+// a Conn from NewConn or WrapConn is not recorded, use DB.Conn
+type LeakDetector struct {
+	MaxAge time.Duration // Report the ones older
+	// Logger of Report, if nil the Logger of the DB which opened
+	// the Conn or Tx, then log.Default()
+	Logger *log.Logger
+
+	mu   sync.Mutex
+	seq  int
+	open map[int]*Leak
+}
+
+// Leak is a Conn or Tx still open
+type Leak struct {
+	Kind     string // Conn or Tx
+	Created  time.Time
+	Stack    string
+	reported bool
+	logger   *log.Logger // of the DB
+}
+
+func NewLeakDetector(maxAge time.Duration) *LeakDetector {
+	return &LeakDetector{
+		MaxAge: maxAge,
+		open:   map[int]*Leak{},
+	}
+}
+
+// track record a Conn or Tx, release must be called when it's closed
+// logger is the one of the DB, for Report
+func (d *LeakDetector) track(kind string, logger *log.Logger) (release func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.open == nil { // &LeakDetector{} without NewLeakDetector
+		d.open = map[int]*Leak{}
+	}
+	d.seq++
+	id := d.seq
+	d.open[id] = &Leak{Kind: kind, Created: time.Now(), Stack: string(debug.Stack()), logger: logger}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			delete(d.open, id)
+			d.mu.Unlock()
+		})
+	}
+}
+
+// Open renvoi les Conn et Tx encore ouverts, les plus anciens en premier
+func (d *LeakDetector) Open() []Leak {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	leaks := make([]Leak, 0, len(d.open))
+	for _, l := range d.open {
+		leaks = append(leaks, *l)
+	}
+	sort.Slice(leaks, func(i, j int) bool { return leaks[i].Created.Before(leaks[j].Created) })
+	return leaks
+}
+
+// Report log the Conn and Tx open since more than MaxAge
+// each one is logged once, it renvoi le nombre de fuites
+func (d *LeakDetector) Report() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, l := range d.open {
+		age := time.Since(l.Created)
+		if age < d.MaxAge {
+			continue
+		}
+		n++
+		if l.reported {
+			continue
+		}
+		l.reported = true
+		logger := d.Logger
+		if logger == nil {
+			logger = l.logger
+		}
+		if logger == nil {
+			logger = log.Default()
+		}
+		logger.Printf("sqlo: %s open since %s, created at\n%s", l.Kind, age.Round(time.Millisecond), l.Stack)
+	}
+	return n
+}
+
+// Watch call Report every interval until ctx is done
+func (d *LeakDetector) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.Report()
+			}
+		}
+	}()
+}
+
+// TB is the part of testing.TB used by AssertNoLeaks
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertNoLeaks fail the test for each Conn or Tx still open
+// typically with t.Cleanup(func() { leaks.AssertNoLeaks(t) })
+func (d *LeakDetector) AssertNoLeaks(t TB) {
+	t.Helper()
+	for _, l := range d.Open() {
+		t.Errorf("sqlo: %s not closed, created at\n%s", l.Kind, l.Stack)
+	}
+}
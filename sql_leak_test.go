@@ -0,0 +1,94 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeTB struct {
+	errors []string
+}
+
+func (t *fakeTB) Helper() {}
+func (t *fakeTB) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestLeakDetector(t *testing.T) {
+	db, _ := openFake(t)
+	x := WrapDB(context.Background(), db)
+	buf := &bytes.Buffer{}
+	x.Leaks = NewLeakDetector(0)
+	x.Leaks.Logger = log.New(buf, "", 0)
+
+	tx, _ := x.Begin()
+	conn, err := x.Conn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, _ := conn.Begin()
+	if n := len(x.Leaks.Open()); n != 3 {
+		t.Errorf("attend 3 ouverts reçoit %d", n)
+	}
+	if n := x.Leaks.Report(); n != 3 {
+		t.Errorf("attend 3 fuites reçoit %d", n)
+	}
+	if !strings.Contains(buf.String(), "sql_leak_test.go") {
+		t.Errorf("la pile doit contenir le test: %s", buf.String())
+	}
+	buf.Reset()
+	x.Leaks.Report()
+	if buf.Len() != 0 {
+		t.Errorf("une fuite n'est loguée qu'une fois")
+	}
+
+	tx.Commit()
+	ctx.Rollback()
+	tb := &fakeTB{}
+	x.Leaks.AssertNoLeaks(tb)
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "Conn not closed") {
+		t.Errorf("attend Conn not closed reçoit %v", tb.errors)
+	}
+	conn.Close()
+	x.Leaks.AssertNoLeaks(t)
+}
+
+func TestLeakDetectorDBLogger(t *testing.T) {
+	db, _ := openFake(t)
+	x := WrapDB(context.Background(), db)
+	buf := &bytes.Buffer{}
+	x.Logger = log.New(buf, "", 0)
+	x.Leaks = NewLeakDetector(0)
+
+	tx, _ := x.Begin()
+	defer tx.Rollback()
+	if n := x.Leaks.Report(); n != 1 {
+		t.Errorf("attend 1 fuite reçoit %d", n)
+	}
+	if !strings.Contains(buf.String(), "sqlo: Tx open since") {
+		t.Errorf("attend le rapport dans le Logger du DB: %q", buf.String())
+	}
+}
+
+func TestLeakDetectorLiteral(t *testing.T) {
+	db, _ := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.Leaks = &LeakDetector{MaxAge: time.Hour}
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(x.Leaks.Open()); n != 1 {
+		t.Errorf("attend 1 ouvert reçoit %d", n)
+	}
+	tx.Rollback()
+	x.Leaks.AssertNoLeaks(t)
+}
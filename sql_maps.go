@@ -0,0 +1,111 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Column of a Table, DatabaseType is given by the driver (TEXT, INT4, NVARCHAR...)
+type Column struct {
+	Name         string
+	DatabaseType string
+}
+
+// Table is the result of SelectTable, the columns are in the order of the query
+type Table struct {
+	Columns []Column
+	Rows    [][]any
+}
+
+// binary_types are kept as []byte, the other []byte are converted to string
+var binary_types = map[string]bool{
+	"BYTEA":      true,
+	"BINARY":     true,
+	"VARBINARY":  true,
+	"IMAGE":      true,
+	"BLOB":       true,
+	"LONGBINARY": true,
+}
+
+// scanTable lit toutes les lignes, max 0 for all
+func scanTable(rows *sqlx.Rows, max int) (*Table, error) {
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{Columns: make([]Column, len(types))}
+	for i, ct := range types {
+		t.Columns[i] = Column{Name: ct.Name(), DatabaseType: ct.DatabaseTypeName()}
+	}
+	for rows.Next() {
+		row, err := rows.SliceScan()
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range row {
+			if b, ok := v.([]byte); ok && !binary_types[strings.ToUpper(t.Columns[i].DatabaseType)] {
+				row[i] = string(b)
+			}
+		}
+		t.Rows = append(t.Rows, row)
+		if max > 0 && len(t.Rows) == max {
+			break
+		}
+	}
+	return t, rows.Err()
+}
+
+// Maps renvoi les lignes en map colonne -> valeur
+func (t *Table) Maps() []map[string]any {
+	maps := make([]map[string]any, len(t.Rows))
+	for r, row := range t.Rows {
+		m := make(map[string]any, len(t.Columns))
+		for i, c := range t.Columns {
+			m[c.Name] = row[i]
+		}
+		maps[r] = m
+	}
+	return maps
+}
+
+// readTable run the query and read its rows, the errors are wrapped with op
+func readTable(x Selecter, op string, query string, args []any, max int) (*Table, error) {
+	rows, cancel, err := openRows(x, query, args...)
+	if err != nil {
+		return nil, rowsErr(x, op, query, args, err)
+	}
+	defer cancel()
+	t, err := scanTable(rows, max)
+	if err != nil {
+		return nil, rowsErr(x, op, query, args, err)
+	}
+	return t, nil
+}
+
+func selectTable(x Selecter, query string, args ...any) (*Table, error) {
+	return readTable(x, "SelectTable", query, args, 0)
+}
+
+func selectMaps(x Selecter, query string, args ...any) ([]map[string]any, error) {
+	t, err := readTable(x, "SelectMaps", query, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return t.Maps(), nil
+}
+
+func getMap(x Selecter, query string, args ...any) (map[string]any, error) {
+	t, err := readTable(x, "GetMap", query, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Rows) == 0 {
+		return nil, rowsErr(x, "GetMap", query, args, sql.ErrNoRows)
+	}
+	return t.Maps()[0], nil
+}
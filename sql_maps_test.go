@@ -0,0 +1,59 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSelectMaps(t *testing.T) {
+	db, f := openFake(t)
+	f.result = func(string, []any) fakeRes {
+		return fakeRes{
+			cols:  []string{"name", "n", "data"},
+			types: []string{"TEXT", "INT8", "BYTEA"},
+			rows: [][]driver.Value{
+				{[]byte("a"), int64(1), []byte{0, 1}},
+				{[]byte("b"), int64(2), nil},
+			},
+		}
+	}
+	var x Selecter = WrapDB(context.Background(), db)
+
+	maps, err := x.SelectMaps("select * from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"name": "a", "n": int64(1), "data": []byte{0, 1}},
+		{"name": "b", "n": int64(2), "data": nil},
+	}
+	if !reflect.DeepEqual(maps, want) {
+		t.Errorf("attend %v reçoit %v", want, maps)
+	}
+
+	m, err := x.GetMap("select * from t")
+	if err != nil || !reflect.DeepEqual(m, want[0]) {
+		t.Errorf("GetMap %v %v", m, err)
+	}
+
+	tbl, err := x.SelectTable("select * from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols := []Column{{"name", "TEXT"}, {"n", "INT8"}, {"data", "BYTEA"}}
+	if !reflect.DeepEqual(tbl.Columns, cols) || len(tbl.Rows) != 2 || tbl.Rows[1][0] != "b" {
+		t.Errorf("SelectTable %+v", tbl)
+	}
+
+	f.result = func(string, []any) fakeRes { return fakeRes{cols: []string{"n"}} }
+	if _, err := x.GetMap("select * from t"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetMap attend ErrNoRows reçoit %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"expvar"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram
+// the last bucket of QueryStats.Buckets count what is above
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// QueryStats count the calls of an operation or of a query
+type QueryStats struct {
+	Count   int64
+	Errors  int64
+	Total   time.Duration
+	Max     time.Duration
+	Buckets []int64 // len(LatencyBuckets)+1
+}
+
+func (s *QueryStats) add(d time.Duration, err error) {
+	if s.Buckets == nil {
+		s.Buckets = make([]int64, len(LatencyBuckets)+1)
+	}
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Total += d
+	if d > s.Max {
+		s.Max = d
+	}
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	s.Buckets[i]++
+}
+
+// Mean renvoi la durée moyenne
+func (s QueryStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// MetricsSnapshot is a copy of the metrics at a time
+// Ops by operation (Select, Exec, InsertMap...)
+// Queries by fingerprint of the query
+type MetricsSnapshot struct {
+	Ops     map[string]QueryStats
+	Queries map[string]QueryStats
+	DBStats sql.DBStats
+}
+
+// Metrics collect the calls of a DB and of its Tx and Conn
+type Metrics struct {
+	db      *sqlx.DB
+	mu      sync.Mutex
+	ops     map[string]*QueryStats
+	queries map[string]*QueryStats
+}
+
+// NewMetrics prepare the collector, db is used for sql.DBStats (can be nil)
+// set it to DB.Metrics
+func NewMetrics(db *sqlx.DB) *Metrics {
+	return &Metrics{
+		db:      db,
+		ops:     map[string]*QueryStats{},
+		queries: map[string]*QueryStats{},
+	}
+}
+
+func (m *Metrics) observe(op string, query string, d time.Duration, err error) {
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	fp := ""
+	if query != "" {
+		fp = Fingerprint(query)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.ops[op]
+	if !ok {
+		s = &QueryStats{}
+		m.ops[op] = s
+	}
+	s.add(d, err)
+	if fp == "" {
+		return
+	}
+	s, ok = m.queries[fp]
+	if !ok {
+		s = &QueryStats{}
+		m.queries[fp] = s
+	}
+	s.add(d, err)
+}
+
+// Snapshot renvoi une copie des compteurs
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	snap := MetricsSnapshot{
+		Ops:     map[string]QueryStats{},
+		Queries: map[string]QueryStats{},
+	}
+	m.mu.Lock()
+	for k, s := range m.ops {
+		snap.Ops[k] = s.clone()
+	}
+	for k, s := range m.queries {
+		snap.Queries[k] = s.clone()
+	}
+	m.mu.Unlock()
+	if m.db != nil {
+		snap.DBStats = m.db.Stats()
+	}
+	return snap
+}
+
+func (s *QueryStats) clone() QueryStats {
+	c := *s
+	c.Buckets = append([]int64{}, s.Buckets...)
+	return c
+}
+
+// Reset remet les compteurs à zéro
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	m.ops = map[string]*QueryStats{}
+	m.queries = map[string]*QueryStats{}
+	m.mu.Unlock()
+}
+
+// Publish export the snapshot with expvar under name
+// like expvar.Publish it panics if name is already used
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return m.Snapshot()
+	}))
+}
+
+var fingerprint_re_string = regexp.MustCompile(`'(?:[^']|'')*'`)
+var fingerprint_re_param = regexp.MustCompile(`\$\d+|@p\d+|\b\d+(?:\.\d+)?\b`)
+var fingerprint_re_space = regexp.MustCompile(`\s+`)
+var fingerprint_re_list = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+
+// Fingerprint normalize a query to group the metrics
+// literals and placeholders become ? and lists (?, ?, ?) become (?)
+func Fingerprint(query string) string {
+	s := fingerprint_re_string.ReplaceAllString(query, "?")
+	s = fingerprint_re_param.ReplaceAllString(s, "?")
+	s = fingerprint_re_space.ReplaceAllString(s, " ")
+	s = fingerprint_re_list.ReplaceAllString(s, "(?)")
+	return strings.ToLower(strings.TrimSpace(s))
+}
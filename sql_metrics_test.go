@@ -0,0 +1,58 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	tbl := [][2]string{
+		{"select * from t where a=$1 and b=$2", "select * from t where a=? and b=?"},
+		{"SELECT *\n  FROM t WHERE a=@p1", "select * from t where a=?"},
+		{"select * from t1 where a='it''s' and b=42 and c in ($1, $2,$3)", "select * from t1 where a=? and b=? and c in (?)"},
+		{"select * from t where c in (?,?)", "select * from t where c in (?)"},
+	}
+	for _, s := range tbl {
+		r := Fingerprint(s[0])
+		if r != s[1] {
+			t.Errorf("de %s attend %s reçoit %s", s[0], s[1], r)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	db, f := openFake(t)
+	f.result = oneRow("n", int64(1))
+	x := WrapDB(context.Background(), db)
+	x.Metrics = NewMetrics(db)
+
+	n := 0
+	x.Get(&n, "select $1", 1)
+	x.Get(&n, "select $1", 2)
+	tx, _ := x.Begin()
+	tx.Exec("update t set a=$1", 3)
+	tx.Commit()
+	f.result = func(string, []any) fakeRes { return fakeRes{err: errors.New("boom")} }
+	x.Exec("update t set a=$1", 4)
+
+	snap := x.Metrics.Snapshot()
+	if s := snap.Ops["Get"]; s.Count != 2 || s.Errors != 0 {
+		t.Errorf("Get %+v", s)
+	}
+	if s := snap.Ops["Exec"]; s.Count != 2 || s.Errors != 1 {
+		t.Errorf("Exec %+v", s)
+	}
+	if s := snap.Ops["Commit"]; s.Count != 1 {
+		t.Errorf("Commit %+v", s)
+	}
+	if s := snap.Queries["update t set a=?"]; s.Count != 2 || s.Errors != 1 || len(s.Buckets) != len(LatencyBuckets)+1 {
+		t.Errorf("update %+v", s)
+	}
+	if snap.DBStats.OpenConnections == 0 {
+		t.Errorf("DBStats %+v", snap.DBStats)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+// bindType renvoi le style de placeholder de sqlx pour dbType
+func bindType(dbType int) int {
+	switch dbType {
+	case DB_ACCESS:
+		return sqlx.QUESTION
+	case DB_MSSQL:
+		return sqlx.AT
+	default:
+		return sqlx.DOLLAR
+	}
+}
+
+// namedSt renvoi la requête avec les :name remplacés par les placeholders
+// de dbType et les valeurs prises dans arg (struct or map)
+// a name used twice give two placeholders, as needed by access
+// like sqlx a :: is an escaped :, a postgres cast is written ::::
+// only the :name are numbered, a ? in a string or a jsonb ? are kept
+func namedSt(dbType int, query string, arg any) (string, []any, error) {
+	return sqlx.BindNamed(bindType(dbType), query, arg)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ Execer = (*Sx)(nil)
+var _ Execer = (*DB)(nil)
+var _ Execer = (*Tx)(nil)
+var _ Execer = (*Conn)(nil)
+
+func Test_namedSt(t *testing.T) {
+	type P struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	p := P{ID: 1, Name: "a"}
+	tbl := []struct {
+		T int
+		Q string
+		S string
+		A []any
+	}{
+		{DB_PG, "update t set name=:name where id=:id", "update t set name=$1 where id=$2", []any{"a", 1}},
+		{DB_MSSQL, "update t set name=:name where id=:id", "update t set name=@p1 where id=@p2", []any{"a", 1}},
+		{DB_ACCESS, "select * from t where id=:id or parent=:id", "select * from t where id=? or parent=?", []any{1, 1}},
+		{DB_PG, "select 'a'::::text, :id", "select 'a'::text, $1", []any{1}},
+		{DB_MSSQL, "select * from t where note = 'why?' and id=:id", "select * from t where note = 'why?' and id=@p1", []any{1}},
+		{DB_PG, "select * from t where j ? 'k' and id=:id", "select * from t where j ? 'k' and id=$1", []any{1}},
+	}
+	for _, s := range tbl {
+		q, args, err := namedSt(s.T, s.Q, p)
+		if err != nil || q != s.S || !reflect.DeepEqual(args, s.A) {
+			t.Errorf("type %d %s attend %s %v reçoit %s %v %v", s.T, s.Q, s.S, s.A, q, args, err)
+		}
+	}
+	if _, _, err := namedSt(DB_PG, "select :nope", p); err == nil {
+		t.Errorf("attend une erreur pour :nope")
+	}
+}
+
+func TestNamed(t *testing.T) {
+	db, f := openFake(t)
+	f.result = oneRow("n", int64(1))
+	buf := &bytes.Buffer{}
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_MSSQL
+	x.Logger = log.New(buf, "", 0)
+
+	arg := map[string]any{"id": 5, "name": "o'k"}
+	if _, err := x.NamedExec("update t set name=:name where id=:id", arg); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	if err := x.NamedGet(&n, "select count(*) from t where id=:id", arg); err != nil || n != 1 {
+		t.Errorf("NamedGet %d %v", n, err)
+	}
+	ns := []int{}
+	if err := x.NamedSelect(&ns, "select n from t where id=:id", arg); err != nil || len(ns) != 1 {
+		t.Errorf("NamedSelect %v %v", ns, err)
+	}
+	want := "update t set name='o''k' where id=5\nselect count(*) from t where id=5\nselect n from t where id=5\n"
+	if buf.String() != want {
+		t.Errorf("log attend %q reçoit %q", want, buf.String())
+	}
+	if q := f.Queries()[0]; q != "update t set name=@p1 where id=@p2" {
+		t.Errorf("requête %s", q)
+	}
+}
+
+func TestSxNamedExec(t *testing.T) {
+	db, f := openFake(t)
+	x := New(sqlx.NewDb(db.DB, "sqlserver")) // DbType non renseigné
+	if _, err := x.NamedExec("update t set a=:a", map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.Queries()[0]; q != "update t set a=@p1" {
+		t.Errorf("attend les placeholders du driver reçoit %s", q)
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// placeholder found in a query by scanPlaceholders
+type placeholder struct {
+	start, end int // query[start:end] is the placeholder
+	n          int // number of $n or @pN, 0 for ?, -1 for ?? (escaped ?)
+}
+
+// scanPlaceholders renvoi les placeholders de la requête selon le style
+// "$" "@p" ou "?", hors des chaines, identifiants entre "" et commentaires
+// brackets skip the identifiers between [] (sqlserver access, not postgres arrays)
+func scanPlaceholders(style string, brackets bool, query string) []placeholder {
+	var phs []placeholder
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i, c)
+		case c == '[' && brackets:
+			i = skipQuoted(query, i, ']')
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(query)
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(query)
+			}
+		case c == '$' && style == "$":
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j > i+1 {
+				n, _ := strconv.Atoi(query[i+1 : j])
+				phs = append(phs, placeholder{i, j, n})
+				i = j - 1
+			} else if tag, ok := dollarTag(query[i:]); ok {
+				// $$ ... $$ or $tag$ ... $tag$
+				if k := strings.Index(query[i+len(tag):], tag); k >= 0 {
+					i += len(tag) + k + len(tag) - 1
+				} else {
+					i = len(query)
+				}
+			}
+		case c == '@' && style == "@p" && strings.HasPrefix(query[i:], "@p"):
+			j := i + 2
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j > i+2 {
+				n, _ := strconv.Atoi(query[i+2 : j])
+				phs = append(phs, placeholder{i, j, n})
+				i = j - 1
+			}
+		case c == '?' && style == "?":
+			if strings.HasPrefix(query[i:], "??") {
+				phs = append(phs, placeholder{i, i + 2, -1})
+				i++
+				continue
+			}
+			phs = append(phs, placeholder{i, i + 1, 0})
+		}
+	}
+	return phs
+}
+
+// skipQuoted renvoi la position de la fin de la chaine ouverte en i
+// a doubled quote is an escaped quote
+func skipQuoted(query string, i int, end byte) int {
+	for j := i + 1; j < len(query); j++ {
+		if query[j] != end {
+			continue
+		}
+		if j+1 < len(query) && query[j+1] == end {
+			j++
+			continue
+		}
+		return j
+	}
+	return len(query)
+}
+
+// dollarTag renvoi $tag$ au début de s (postgres dollar quoting)
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		if c == '$' {
+			return s[:j+1], true
+		}
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// placeholderStyle renvoi le style de placeholder de dbType
+func placeholderStyle(dbType int) string {
+	switch dbType {
+	case DB_ACCESS:
+		return "?"
+	case DB_MSSQL:
+		return "@p"
+	default:
+		return "$"
+	}
+}
+
+// formatPlaceholder renvoi le placeholder n dans le style
+func formatPlaceholder(style string, n int) string {
+	switch style {
+	case "?":
+		return "?"
+	case "@p":
+		return fmt.Sprintf("@p%d", n)
+	default:
+		return fmt.Sprintf("$%d", n)
+	}
+}
+
+// portable_ops are the ops of the queries written by the caller
+// rebound in Portable mode
+var portable_ops = map[string]bool{
+	"Select": true,
+	"Get":    true,
+	"Exec":   true,
+	"Query":  true,
+}
+
+// rewrite the query of op before running it according to the options of env
+func (e env) rewrite(op string, query string, args []any) (string, []any, error) {
+	if e.portable && portable_ops[op] {
+		query = rebind(placeholderStyle(e.dbType), query)
+	}
+	if e.dbType == DB_ACCESS {
+		var err error
+		query, args, err = dollarToQuestion(query, args)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if e.expandSlices {
+		return expandSlices(placeholderStyle(e.dbType), query, args)
+	}
+	return query, args, nil
+}
+
+// bindWhere rebind the where of UpdateMap in Portable mode
+// numbered from 1 as expected by updateSt
+// with access the $n are rewritten in ? and the vals reordered
+// before updateSt add the ? of the SET
+func (e env) bindWhere(where string, vals []any) (string, []any, error) {
+	if e.portable {
+		where = rebind(placeholderStyle(e.dbType), where)
+	}
+	if e.dbType == DB_ACCESS {
+		return dollarToQuestion(where, vals)
+	}
+	return where, vals, nil
+}
+
+// rebind replace the ? placeholders by the ones of style, numbered from 1
+// ?? is an escaped ?, for the jsonb operators of postgres
+// the ? in strings, quoted identifiers and comments are kept
+func rebind(style string, query string) string {
+	phs := scanPlaceholders("?", style != "$", query)
+	if len(phs) == 0 {
+		return query
+	}
+	b := strings.Builder{}
+	last := 0
+	n := 0
+	for _, ph := range phs {
+		b.WriteString(query[last:ph.start])
+		last = ph.end
+		if ph.n < 0 {
+			b.WriteString("?")
+			continue
+		}
+		n++
+		b.WriteString(formatPlaceholder(style, n))
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// sliceArg renvoi la valeur du slice à développer
+// not []byte nor a driver.Valuer like pq.Array
+func sliceArg(a any) (reflect.Value, bool) {
+	if a == nil {
+		return reflect.Value{}, false
+	}
+	if _, ok := a.(driver.Valuer); ok {
+		return reflect.Value{}, false
+	}
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return reflect.Value{}, false
+	}
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+var expand_re_notin = regexp.MustCompile(`(?i)\bnot\s+in\s*\(\s*$`)
+
+// expandSlices replace the placeholder of a slice argument by as many
+// placeholders as values, the following placeholders are renumbered
+// an empty slice become NULL, so "in (NULL)" match nothing
+// but it's an error after a not in, "not in (NULL)" would match nothing too
+//
+//	"id in ($1) and a=$2", []int{4, 5}, "a" -> "id in ($1,$2) and a=$3", 4, 5, "a"
+func expandSlices(style string, query string, args []any) (string, []any, error) {
+	expand := false
+	for _, a := range args {
+		if _, ok := sliceArg(a); ok {
+			expand = true
+			break
+		}
+	}
+	if !expand {
+		return query, args, nil
+	}
+	phs := scanPlaceholders(style, style != "$", query)
+
+	// new position and count of each arg
+	starts := make([]int, len(args))
+	counts := make([]int, len(args))
+	newArgs := make([]any, 0, len(args))
+	for i, a := range args {
+		starts[i] = len(newArgs) + 1
+		if v, ok := sliceArg(a); ok {
+			counts[i] = v.Len()
+			for j := 0; j < v.Len(); j++ {
+				newArgs = append(newArgs, v.Index(j).Interface())
+			}
+			continue
+		}
+		counts[i] = 1
+		newArgs = append(newArgs, a)
+	}
+
+	b := strings.Builder{}
+	last := 0
+	k := 0
+	for _, ph := range phs {
+		if ph.n < 0 {
+			continue
+		}
+		i := ph.n - 1
+		if style == "?" {
+			i = k
+			k++
+		}
+		if i < 0 || i >= len(args) {
+			return "", nil, fmt.Errorf("placeholder %s without argument", query[ph.start:ph.end])
+		}
+		b.WriteString(query[last:ph.start])
+		last = ph.end
+		if counts[i] == 0 {
+			if expand_re_notin.MatchString(query[:ph.start]) {
+				return "", nil, fmt.Errorf("empty slice for %s in not in", query[ph.start:ph.end])
+			}
+			b.WriteString("NULL")
+			continue
+		}
+		for j := 0; j < counts[i]; j++ {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(formatPlaceholder(style, starts[i]+j))
+		}
+	}
+	b.WriteString(query[last:])
+	return b.String(), newArgs, nil
+}
+
+// dollarToQuestion rewrite the $n of query in ? for access which only
+// know positional placeholders, the args are reordered and duplicated
+// as the $n are used, so the same query work with postgres and access
+//
+//	"a=$2 or b=$1 or c=$2", "x", "y" -> "a=? or b=? or c=?", "y", "x", "y"
+func dollarToQuestion(query string, args []any) (string, []any, error) {
+	phs := scanPlaceholders("$", true, query)
+	if len(phs) == 0 {
+		return query, args, nil
+	}
+	for _, ph := range scanPlaceholders("?", true, query) {
+		if ph.n == 0 {
+			return "", nil, fmt.Errorf("mix of ? and $n placeholders")
+		}
+	}
+	b := strings.Builder{}
+	newArgs := make([]any, 0, len(phs))
+	last := 0
+	for _, ph := range phs {
+		i := ph.n - 1
+		if i < 0 || i >= len(args) {
+			return "", nil, fmt.Errorf("placeholder %s without argument", query[ph.start:ph.end])
+		}
+		b.WriteString(query[last:ph.start])
+		b.WriteString("?")
+		last = ph.end
+		newArgs = append(newArgs, args[i])
+	}
+	b.WriteString(query[last:])
+	return b.String(), newArgs, nil
+}
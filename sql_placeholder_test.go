@@ -0,0 +1,189 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func Test_scanPlaceholders(t *testing.T) {
+	tbl := []struct {
+		style string
+		q     string
+		n     []int
+	}{
+		{"$", "a=$1 and b='$2' and c=$3 -- $4\nand d=$10", []int{1, 3, 10}},
+		{"$", `select $$ $1 $$, $tag$ $2 $tag$, "x$3", $4`, []int{4}},
+		{"$", "select 'it''s $1', $2 /* $3 */", []int{2}},
+		{"@p", "a=@p1 and [x @p2]=@p3 and b='@p4'", []int{1, 3}},
+		{"?", "a=? and b='?' and c=?", []int{0, 0}},
+	}
+	for _, s := range tbl {
+		n := []int{}
+		for _, ph := range scanPlaceholders(s.style, s.style != "$", s.q) {
+			n = append(n, ph.n)
+		}
+		if !reflect.DeepEqual(n, s.n) {
+			t.Errorf("%s attend %v reçoit %v", s.q, s.n, n)
+		}
+	}
+}
+
+func Test_expandSlices(t *testing.T) {
+	tbl := []struct {
+		style string
+		q     string
+		args  []any
+		s     string
+		a     []any
+	}{
+		{"$", "id in ($1) and a=$2", []any{[]int{4, 5}, "a"}, "id in ($1,$2) and a=$3", []any{4, 5, "a"}},
+		{"$", "a=$1 and id in ($2) and b=$1", []any{"a", []string{"x", "y"}}, "a=$1 and id in ($2,$3) and b=$1", []any{"a", "x", "y"}},
+		{"@p", "id in (@p1) and a=@p2", []any{[]int{4, 5}, "a"}, "id in (@p1,@p2) and a=@p3", []any{4, 5, "a"}},
+		{"?", "id in (?) and a=?", []any{[]int{4, 5}, "a"}, "id in (?,?) and a=?", []any{4, 5, "a"}},
+		{"$", "id in ($1) and a=$2", []any{[]int{}, "a"}, "id in (NULL) and a=$1", []any{"a"}},
+		{"$", "b=$1 and id=any($2)", []any{[]byte("b"), pq.Array([]int{1})}, "b=$1 and id=any($2)", []any{[]byte("b"), pq.Array([]int{1})}},
+	}
+	for _, s := range tbl {
+		q, args, err := expandSlices(s.style, s.q, s.args)
+		if err != nil || q != s.s || !reflect.DeepEqual(args, s.a) {
+			t.Errorf("%s %v attend %s %v reçoit %s %v %v", s.q, s.args, s.s, s.a, q, args, err)
+		}
+	}
+	if _, _, err := expandSlices("$", "id not in ($1)", []any{[]int{}}); err == nil {
+		t.Errorf("attend une erreur pour not in avec un slice vide")
+	}
+	if q, _, err := expandSlices("?", "id NOT IN (?)", []any{[]int{1, 2}}); err != nil || q != "id NOT IN (?,?)" {
+		t.Errorf("not in %s %v", q, err)
+	}
+	if _, _, err := expandSlices("$", "a in ($2)", []any{[]int{1}}); err == nil {
+		t.Errorf("attend une erreur pour $2 sans argument")
+	}
+}
+
+func TestExpandSlices(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.Exec("delete from t where id in ($1)", []int{1, 2})
+	x.ExpandSlices = true
+	tx, _ := x.Begin()
+	tx.Exec("delete from t where id in ($1)", []int{1, 2})
+	tx.Commit()
+	q := f.Queries()
+	if q[0] != "delete from t where id in ($1)" || q[2] != "delete from t where id in ($1,$2)" {
+		t.Errorf("reçoit %v", q)
+	}
+	if !reflect.DeepEqual(f.args[2], []any{1, 2}) {
+		t.Errorf("args %v", f.args[2])
+	}
+}
+
+func Test_rebind(t *testing.T) {
+	tbl := []struct {
+		style string
+		q     string
+		s     string
+	}{
+		{"$", "a=? and b='?' and c=?", "a=$1 and b='?' and c=$2"},
+		{"@p", "a=? and [x?]=? -- ?", "a=@p1 and [x?]=@p2 -- ?"},
+		{"?", "a=? and b=?", "a=? and b=?"},
+		{"$", "data ?? 'k' and data ??| array[?]", "data ? 'k' and data ?| array[$1]"},
+		{"?", "a ?? b", "a ? b"},
+	}
+	for _, s := range tbl {
+		if q := rebind(s.style, s.q); q != s.s {
+			t.Errorf("%s %s attend %s reçoit %s", s.style, s.q, s.s, q)
+		}
+	}
+}
+
+func TestPortable(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_MSSQL
+	x.Portable = true
+	x.ExpandSlices = true
+	n := 0
+	x.Get(&n, "select count(*) from t where a=? and id in (?)", "a", []int{1, 2})
+	x.UpdateMap("t", map[string]any{"b": 1}, "a=?", "a")
+	x.NamedExec("update t set b=:b", map[string]any{"b": 1})
+	want := []string{
+		"select count(*) from t where a=@p1 and id in (@p2,@p3)",
+		"UPDATE t SET b=@p2 WHERE a=@p1",
+		"update t set b=@p1",
+	}
+	if q := f.Queries(); !reflect.DeepEqual(q, want) {
+		t.Errorf("attend %v\nreçoit %v", want, q)
+	}
+}
+
+func Test_dollarToQuestion(t *testing.T) {
+	q, args, err := dollarToQuestion("a=$2 or b=$1 or c=$2 or d='$3'", []any{"x", "y"})
+	if err != nil || q != "a=? or b=? or c=? or d='$3'" || !reflect.DeepEqual(args, []any{"y", "x", "y"}) {
+		t.Errorf("reçoit %s %v %v", q, args, err)
+	}
+	q, args, _ = dollarToQuestion("a=? and b=?", []any{"x", "y"})
+	if q != "a=? and b=?" || len(args) != 2 {
+		t.Errorf("sans $n reçoit %s %v", q, args)
+	}
+	if _, _, err := dollarToQuestion("a=? and b=$1", []any{"x"}); err == nil {
+		t.Errorf("attend une erreur pour ? et $n")
+	}
+	if _, _, err := dollarToQuestion("a=$3", []any{"x"}); err == nil {
+		t.Errorf("attend une erreur pour $3")
+	}
+}
+
+func TestAccessDollar(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_ACCESS
+	x.ExpandSlices = true
+	x.Exec("update t set a=$2 where id in ($1) or parent in ($1)", []int{1, 2}, "a")
+	x.UpdateMap("t", map[string]any{"b": 1}, "a=?", "a")
+	want := []string{
+		"update t set a=? where id in (?,?) or parent in (?,?)",
+		"UPDATE t SET b=? WHERE a=?",
+	}
+	if q := f.Queries(); !reflect.DeepEqual(q, want) {
+		t.Errorf("attend %v\nreçoit %v", want, q)
+	}
+	if !reflect.DeepEqual(f.args[0], []any{"a", 1, 2, 1, 2}) {
+		t.Errorf("args %v", f.args[0])
+	}
+}
+
+func TestAccessDollarUpdateMap(t *testing.T) {
+	db, f := openFake(t)
+	f.result = func(string, []any) fakeRes { return fakeRes{affected: 1} }
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_ACCESS
+	if _, err := x.UpdateMap("t", map[string]any{"a": 1}, "id=$2 and (b=$1 or c=$1)", "x", 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x.UpdateMapVersion("t", map[string]any{"a": 1}, "version", 3, "id=$1", 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x.Delete("t", "id=$2 or id=$1", 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"UPDATE t SET a=? WHERE id=? and (b=? or c=?)",
+		"UPDATE t SET a=?, version=version+1 WHERE (id=?) AND version=?",
+		"DELETE FROM t WHERE id=? or id=?",
+	}
+	wantArgs := [][]any{{1, 5, "x", "x"}, {1, 5, 3}, {2, 1}}
+	for i, q := range f.Queries() {
+		if q != want[i] {
+			t.Errorf("attend %s\nreçoit %s", want[i], q)
+		}
+		if !reflect.DeepEqual(f.args[i], wantArgs[i]) {
+			t.Errorf("args %v", f.args[i])
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// QueryError is returned by the statements of DB Tx Conn
+// it unwrap to the error of the driver (or DBError, sql.ErrNoRows...)
+type QueryError struct {
+	Op    string // Select, Get, Exec, InsertMap...
+	Query string // as sent to the database
+	Args  []any  // nil with RedactArgs
+	Fake  string // the query rendered by sql_fake, without the values with RedactArgs
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s: %v (%s)", e.Op, e.Err, e.Fake)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// queryErr wrap err with the query
+func (e env) queryErr(op string, query string, args []any, err error) error {
+	qe := &QueryError{Op: op, Query: query, Fake: query, Err: err}
+	if !e.redactArgs {
+		qe.Args = args
+		qe.Fake = sql_fake(e.dbType, query, args...)
+	}
+	return qe
+}
+
+// queryErrer is implemented by DB Tx Conn Sx to wrap the errors of the
+// helpers which read the rows (Rows SelectTable GetMap...)
+type queryErrer interface {
+	queryErr(op string, query string, args []any, err error) error
+}
+
+// rowsErr wrap err with the query if x can, not if it's already a *QueryError
+func rowsErr(x Selecter, op string, query string, args []any, err error) error {
+	var qe *QueryError
+	if err == nil || errors.As(err, &qe) {
+		return err
+	}
+	if q, ok := x.(queryErrer); ok {
+		return q.queryErr(op, query, args, err)
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestQueryError(t *testing.T) {
+	db, f := openFake(t)
+	f.result = func(string, []any) fakeRes { return fakeRes{cols: []string{"n"}} }
+	x := WrapDB(context.Background(), db)
+
+	n := 0
+	err := x.Get(&n, "select n from t where a=$1", "secret")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("attend ErrNoRows reçoit %v", err)
+	}
+	var qe *QueryError
+	if !errors.As(err, &qe) {
+		t.Fatalf("attend QueryError reçoit %T", err)
+	}
+	if qe.Op != "Get" || qe.Query != "select n from t where a=$1" {
+		t.Errorf("QueryError %+v", qe)
+	}
+	if len(qe.Args) != 1 || qe.Args[0] != "secret" {
+		t.Errorf("Args %v", qe.Args)
+	}
+	if qe.Fake != "select n from t where a='secret'" {
+		t.Errorf("Fake %s", qe.Fake)
+	}
+
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	f.result = func(string, []any) fakeRes { return fakeRes{err: &pq.Error{Code: "23505"}} }
+	_, err = tx.InsertMap("t", map[string]any{"a": "b"})
+	if !errors.As(err, &qe) || qe.Op != "InsertMap" {
+		t.Fatalf("attend QueryError InsertMap reçoit %v", err)
+	}
+	if !IsUniqueViolation(err) {
+		t.Errorf("IsUniqueViolation à travers QueryError")
+	}
+	var dbe *DBError
+	if !errors.As(err, &dbe) {
+		t.Errorf("DBError à travers QueryError")
+	}
+
+	x.RedactArgs = true
+	_, err = x.Exec("update t set a=$1", "secret")
+	if !errors.As(err, &qe) {
+		t.Fatalf("attend QueryError reçoit %v", err)
+	}
+	if qe.Args != nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("args non masqués: %v", err)
+	}
+	if qe.Fake != "update t set a=$1" {
+		t.Errorf("Fake %s", qe.Fake)
+	}
+}
+
+func TestQueryErrorRewrite(t *testing.T) {
+	db, _ := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_ACCESS
+	_, err := x.Exec("update t set a=? where id=$1", 1, 2)
+	var qe *QueryError
+	if !errors.As(err, &qe) {
+		t.Fatalf("attend QueryError reçoit %v", err)
+	}
+	if qe.Query != "update t set a=? where id=$1" || len(qe.Args) != 2 || qe.Fake == "" {
+		t.Errorf("requête perdue %+v", qe)
+	}
+}
+
+func TestQueryErrorRows(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	var qe *QueryError
+
+	f.result = oneRow("n", "abc")
+	var err error
+	for _, err = range Rows[int](x, "select n from t where a=$1", 1) {
+	}
+	if !errors.As(err, &qe) || qe.Op != "Rows" || qe.Fake != "select n from t where a=1" {
+		t.Errorf("Rows attend QueryError reçoit %v", err)
+	}
+
+	f.result = func(string, []any) fakeRes { return fakeRes{cols: []string{"n"}} }
+	_, err = x.GetMap("select n from t where a=$1", 1)
+	if !errors.Is(err, sql.ErrNoRows) || !errors.As(err, &qe) || qe.Op != "GetMap" {
+		t.Errorf("GetMap attend QueryError ErrNoRows reçoit %v", err)
+	}
+
+	f.result = func(string, []any) fakeRes { return fakeRes{err: errors.New("boom")} }
+	_, err = x.SelectTable("select n from t")
+	if !errors.As(err, &qe) || qe.Op != "Query" {
+		t.Errorf("SelectTable attend QueryError reçoit %v", err)
+	}
+	if strings.Count(err.Error(), "select n from t") != 1 {
+		t.Errorf("QueryError en double: %v", err)
+	}
+}
+
+func TestQueryErrorSx(t *testing.T) {
+	db, f := openFake(t)
+	x := New(db)
+	f.result = func(string, []any) fakeRes { return fakeRes{err: errors.New("boom")} }
+	var qe *QueryError
+	if _, err := x.Exec("update t set a=$1", 1); !errors.As(err, &qe) || qe.Op != "Exec" || qe.Fake != "update t set a=1" {
+		t.Errorf("Exec attend QueryError reçoit %v", err)
+	}
+	n := 0
+	if err := x.Get(&n, "select n from t"); !errors.As(err, &qe) || qe.Op != "Get" {
+		t.Errorf("Get attend QueryError reçoit %v", err)
+	}
+
+	f.result = oneRow("n", "abc")
+	var ns []int
+	if err := x.Select(&ns, "select n from t"); !errors.As(err, &qe) || qe.Op != "Select" {
+		t.Errorf("Select attend QueryError reçoit %v", err)
+	}
+	_, err := x.SelectMaps("select n from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.result = func(string, []any) fakeRes { return fakeRes{cols: []string{"n"}} }
+	if _, err := x.GetMap("select n from t"); !errors.As(err, &qe) || qe.Op != "GetMap" {
+		t.Errorf("GetMap attend QueryError reçoit %v", err)
+	}
+}
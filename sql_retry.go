@@ -0,0 +1,71 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Retry is the policy of InTxRetry
+// zero values use the defaults
+type Retry struct {
+	MaxAttempts int           // 3 by default
+	Backoff     time.Duration // wait before the 2nd attempt, doubled after, 10ms by default
+	MaxBackoff  time.Duration // 1s by default
+	// OnRetry is called before waiting for the next attempt
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// wait renvoi l'attente avant l'essai suivant
+// la moitié est aléatoire pour ne pas relancer toutes les tx ensemble
+func (r Retry) wait(attempt int) time.Duration {
+	d := r.Backoff
+	if d <= 0 {
+		d = 10 * time.Millisecond
+	}
+	max := r.MaxBackoff
+	if max <= 0 {
+		max = time.Second
+	}
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// isRetryable is true for serialization failures and deadlocks
+func isRetryable(err error) bool {
+	return IsSerializationFailure(err) || IsDeadlock(err)
+}
+
+// retryTx run fn in a new transaction until it's not a retryable error
+func retryTx(ctx context.Context, r Retry, begin func() (*Tx, error), fn func(*Tx) error) error {
+	max := r.MaxAttempts
+	if max <= 0 {
+		max = 3
+	}
+	for attempt := 1; ; attempt++ {
+		tx, err := begin()
+		if err == nil {
+			err = inTx(tx, fn)
+		}
+		if err == nil || attempt >= max || !isRetryable(err) {
+			return err
+		}
+		wait := r.wait(attempt)
+		if r.OnRetry != nil {
+			r.OnRetry(attempt, err, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (%w)", ctx.Err(), err)
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type msError struct{ n int32 }
+
+func (e msError) Error() string         { return "mssql error" }
+func (e msError) SQLErrorNumber() int32 { return e.n }
+
+func TestIsRetryable(t *testing.T) {
+	tbl := []struct {
+		err error
+		ok  bool
+	}{
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{msError{1205}, true},
+		{msError{2627}, false},
+		{errors.New("x"), false},
+	}
+	for _, s := range tbl {
+		if isRetryable(s.err) != s.ok {
+			t.Errorf("%v attend %v", s.err, s.ok)
+		}
+	}
+}
+
+func TestInTxRetry(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	fails := 2
+	f.result = func(q string, _ []any) fakeRes {
+		if q == "update t" && fails > 0 {
+			fails--
+			return fakeRes{err: &pq.Error{Code: "40001"}}
+		}
+		return fakeRes{}
+	}
+	retries := 0
+	r := Retry{
+		Backoff: time.Microsecond,
+		OnRetry: func(attempt int, err error, wait time.Duration) { retries++ },
+	}
+	err := x.InTxRetry(r, func(tx *Tx) error {
+		_, err := tx.Exec("update t")
+		return err
+	})
+	if err != nil || retries != 2 {
+		t.Errorf("err %v retries %d", err, retries)
+	}
+
+	fails = 5
+	err = x.InTxRetry(r, func(tx *Tx) error {
+		_, err := tx.Exec("update t")
+		return err
+	})
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || fails != 2 {
+		t.Errorf("attend 3 essais reçoit %v reste %d", err, fails)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fails = 5
+	err = retryTx(ctx, Retry{Backoff: time.Hour}, x.Begin, func(tx *Tx) error {
+		return &pq.Error{Code: "40P01"}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("attend canceled reçoit %v", err)
+	}
+}
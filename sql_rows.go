@@ -0,0 +1,69 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"iter"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Rows itère sur les lignes de la requête sans tout charger
+// each row is scanned in a T (struct or scalar)
+// the rows are closed at the end or on break
+// the errors are *QueryError with DB Tx Conn Sx
+//
+//	for row, err := range sqlo.Rows[Invoice](x, "select * from invoice") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Rows[T any](x Selecter, query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, cancel, err := openRows(x, query, args...)
+		if err != nil {
+			yield(zero, rowsErr(x, "Rows", query, args, err))
+			return
+		}
+		defer cancel()
+		defer rows.Close()
+		for rows.Next() {
+			var v T
+			if err := scanRow(rows, &v); err != nil {
+				yield(zero, rowsErr(x, "Rows", query, args, err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, rowsErr(x, "Rows", query, args, err))
+		}
+	}
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// scanRow scan a struct by name like Select or a scalar
+func scanRow(rows *sqlx.Rows, dest any) error {
+	t := reflect.TypeOf(dest).Elem()
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(scannerType) || !hasExported(t) {
+		return rows.Scan(dest)
+	}
+	return rows.StructScan(dest)
+}
+
+// hasExported is false for struct like time.Time, scanned as a value
+func hasExported(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
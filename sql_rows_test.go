@@ -0,0 +1,73 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	db, f := openFake(t)
+	buf := &bytes.Buffer{}
+	x := WrapDB(context.Background(), db)
+	x.Logger = log.New(buf, "", 0)
+	type Row struct {
+		ID   int64
+		Name string
+	}
+	f.result = func(string, []any) fakeRes {
+		return fakeRes{cols: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}}}
+	}
+	got := []Row{}
+	for row, err := range Rows[Row](x, "select id, name from t where id>$1", 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+		if row.ID == 2 {
+			break
+		}
+	}
+	if len(got) != 2 || got[1] != (Row{2, "b"}) {
+		t.Errorf("Rows %v", got)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("les lignes doivent être fermées, %d connexion(s) en cours", n)
+	}
+	if buf.String() != "select id, name from t where id>0\n" {
+		t.Errorf("log %q", buf.String())
+	}
+
+	f.result = func(string, []any) fakeRes {
+		return fakeRes{cols: []string{"id"}, rows: [][]driver.Value{{int64(1)}, {int64(2)}, {int64(3)}}}
+	}
+	ids := []int64{}
+	for id, err := range Rows[int64](x, "select id from t") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 3 {
+		t.Errorf("Rows scalaire %v", ids)
+	}
+
+	boom := errors.New("boom")
+	f.result = func(string, []any) fakeRes { return fakeRes{err: boom} }
+	n := 0
+	for _, err := range Rows[int64](x, "select id from t") {
+		if !errors.Is(err, boom) {
+			t.Errorf("attend boom reçoit %v", err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("une seule erreur attendue")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// dbTyper is implemented by the wrappers for the helpers taking a Selecter
+type dbTyper interface {
+	dbType() int
+}
+
+func (x *Sx) dbType() int   { return x.DbType }
+func (x *DB) dbType() int   { return x.DbType }
+func (x *Tx) dbType() int   { return x.DbType }
+func (x *Conn) dbType() int { return x.DbType }
+
+func selecterDbType(x Selecter) int {
+	if d, ok := x.(dbTyper); ok {
+		return d.dbType()
+	}
+	return DB_PG
+}
+
+// whereSt renvoi " where ..." et les args, w can be nil
+func whereSt(w *Where) (string, []any) {
+	if w == nil {
+		return "", nil
+	}
+	return w.Where(), w.Args
+}
+
+// renvoi la requête de Exists, LIMIT 1 avec postgres sinon TOP 1
+// table is checked and quoted by QuoteIdent
+func existsSt(dbType int, table string, w *Where) (string, []any, error) {
+	table, err := QuoteIdent(dbType, table)
+	if err != nil {
+		return "", nil, err
+	}
+	where, args := whereSt(w)
+	if dbType == DB_PG {
+		return "SELECT 1 FROM " + table + where + " LIMIT 1", args, nil
+	}
+	return "SELECT TOP 1 1 FROM " + table + where, args, nil
+}
+
+func exists(x Selecter, table string, w *Where) (bool, error) {
+	s, args, err := existsSt(selecterDbType(x), table, w)
+	if err != nil {
+		return false, fmt.Errorf("Exists: %w", err)
+	}
+	one := 0
+	err = x.Get(&one, s, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func count(x Selecter, table string, w *Where) (int64, error) {
+	table, err := QuoteIdent(selecterDbType(x), table)
+	if err != nil {
+		return 0, fmt.Errorf("Count: %w", err)
+	}
+	where, args := whereSt(w)
+	var n int64
+	err = x.Get(&n, "SELECT COUNT(*) FROM "+table+where, args...)
+	return n, err
+}
+
+// Pluck renvoi les valeurs de column des lignes de table
+// w can be nil, its Style must be the one of the DbType
+// table and column are checked and quoted by QuoteIdent
+func Pluck[T any](x Selecter, table string, column string, w *Where) ([]T, error) {
+	dbType := selecterDbType(x)
+	table, err := QuoteIdent(dbType, table)
+	if err != nil {
+		return nil, fmt.Errorf("Pluck: %w", err)
+	}
+	column, err = QuoteIdent(dbType, column)
+	if err != nil {
+		return nil, fmt.Errorf("Pluck: %w", err)
+	}
+	where, args := whereSt(w)
+	return Select[T](x, "SELECT "+column+" FROM "+table+where, args...)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_existsSt(t *testing.T) {
+	w := &Where{}
+	w.And("a=%s", 1)
+	q, args, _ := existsSt(DB_PG, "t", w)
+	if q != "SELECT 1 FROM t where a=$1 LIMIT 1" || len(args) != 1 {
+		t.Errorf("pg: %s %v", q, args)
+	}
+	w = &Where{Style: "@p"}
+	w.And("a=%s", 1)
+	q, _, _ = existsSt(DB_MSSQL, "t", w)
+	if q != "SELECT TOP 1 1 FROM t where a=@p1" {
+		t.Errorf("mssql: %s", q)
+	}
+	q, _, _ = existsSt(DB_ACCESS, "user", nil)
+	if q != "SELECT TOP 1 1 FROM [user]" {
+		t.Errorf("access: %s", q)
+	}
+	if _, _, err := existsSt(DB_PG, "t; drop table t", nil); !errors.Is(err, ErrInvalidIdent) {
+		t.Errorf("attend ErrInvalidIdent reçoit %v", err)
+	}
+}
+
+func TestExistsCountPluck(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	f.result = oneRow("n", int64(3))
+
+	w := &Where{}
+	w.And("a=%s", 1)
+	ok, err := x.Exists("t", w)
+	if !ok || err != nil {
+		t.Errorf("Exists %v %v", ok, err)
+	}
+	n, err := x.Count("t", w)
+	if n != 3 || err != nil {
+		t.Errorf("Count %v %v", n, err)
+	}
+	f.result = func(string, []any) fakeRes {
+		return fakeRes{cols: []string{"name"}, rows: [][]driver.Value{{"a"}, {"b"}}}
+	}
+	names, err := Pluck[string](x, "t", "name", w)
+	if len(names) != 2 || names[1] != "b" || err != nil {
+		t.Errorf("Pluck %v %v", names, err)
+	}
+	f.result = func(string, []any) fakeRes { return fakeRes{cols: []string{"n"}} }
+	ok, err = x.Exists("t", nil)
+	if ok || err != nil {
+		t.Errorf("Exists vide %v %v", ok, err)
+	}
+	if _, err := x.Count("t where 1=1", nil); !errors.Is(err, ErrInvalidIdent) {
+		t.Errorf("Count attend ErrInvalidIdent reçoit %v", err)
+	}
+	if _, err := Pluck[string](x, "t", "name, pass", nil); !errors.Is(err, ErrInvalidIdent) {
+		t.Errorf("Pluck attend ErrInvalidIdent reçoit %v", err)
+	}
+	if _, err := Pluck[string](x, "user", "order", nil); err != nil {
+		t.Error(err)
+	}
+	want := "SELECT 1 FROM t where a=$1 LIMIT 1|SELECT COUNT(*) FROM t where a=$1|SELECT name FROM t where a=$1|SELECT 1 FROM t LIMIT 1|SELECT \"order\" FROM \"user\""
+	if q := strings.Join(f.Queries(), "|"); q != want {
+		t.Errorf("attend %s\nreçoit %s", want, q)
+	}
+}
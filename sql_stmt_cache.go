@@ -0,0 +1,229 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// preparer is a *sqlx.DB or a *sqlx.Conn
+type preparer interface {
+	PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error)
+}
+
+// queryExecer is what the statements are run on
+// *sqlx.DB *sqlx.Tx *sqlx.Conn or a prepared statement
+type queryExecer interface {
+	sqlx.QueryerContext
+	sqlx.ExecerContext
+}
+
+// StmtCacheStats are the counters of a StmtCache
+type StmtCacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Len       int
+}
+
+// StmtCache keep the last prepared statements of Select Get Exec
+// (and InsertMap UpdateMap...) by query text, Query is not cached
+// set it with DB.CacheStatements or Conn.CacheStatements
+// the Tx of the DB or Conn use the statements already in it with Stmtx
+type StmtCache struct {
+	p    preparer
+	size int
+
+	mu    sync.Mutex
+	lru   *list.List // of *cachedStmt, most recent first
+	stmts map[string]*list.Element
+	stats StmtCacheStats
+}
+
+type cachedStmt struct {
+	query   string
+	st      *sqlx.Stmt
+	refs    int  // running statements
+	evicted bool // closed when refs is back to 0
+}
+
+func newStmtCache(p preparer, size int) *StmtCache {
+	if size < 1 {
+		size = 1
+	}
+	return &StmtCache{
+		p:     p,
+		size:  size,
+		lru:   list.New(),
+		stmts: map[string]*list.Element{},
+	}
+}
+
+// Stats renvoi les compteurs
+func (c *StmtCache) Stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Len = c.lru.Len()
+	return s
+}
+
+// Clear close all the statements, the cache can still be used
+func (c *StmtCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		errs = append(errs, c.evict(e.Value.(*cachedStmt)))
+	}
+	c.lru.Init()
+	c.stmts = map[string]*list.Element{}
+	return errors.Join(errs...)
+}
+
+// evict close cs now or when the last user release it
+// c.mu must be held
+func (c *StmtCache) evict(cs *cachedStmt) error {
+	cs.evicted = true
+	if cs.refs == 0 {
+		return cs.st.Close()
+	}
+	return nil
+}
+
+// lookup renvoi the statement of query if it's in the cache
+// release must be called after the statement
+func (c *StmtCache) lookup(query string) (st *sqlx.Stmt, release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.stmts[query]
+	if !ok {
+		c.stats.Misses++
+		return nil, nil, false
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(e)
+	cs := e.Value.(*cachedStmt)
+	cs.refs++
+	return cs.st, c.releaser(cs), true
+}
+
+// get renvoi the statement of query, prepared if it's not in the cache
+// release must be called after the statement
+func (c *StmtCache) get(ctx context.Context, query string) (st *sqlx.Stmt, release func(), err error) {
+	c.mu.Lock()
+	if e, ok := c.stmts[query]; ok {
+		c.stats.Hits++
+		c.lru.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.refs++
+		c.mu.Unlock()
+		return cs.st, c.releaser(cs), nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	st, err = c.p.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.stmts[query]; ok {
+		// prepared meanwhile by an other call
+		st.Close()
+		cs := e.Value.(*cachedStmt)
+		cs.refs++
+		return cs.st, c.releaser(cs), nil
+	}
+	cs := &cachedStmt{query: query, st: st, refs: 1}
+	c.stmts[query] = c.lru.PushFront(cs)
+	for c.lru.Len() > c.size {
+		e := c.lru.Back()
+		old := e.Value.(*cachedStmt)
+		c.lru.Remove(e)
+		delete(c.stmts, old.query)
+		c.stats.Evictions++
+		c.evict(old)
+	}
+	return st, c.releaser(cs), nil
+}
+
+func (c *StmtCache) releaser(cs *cachedStmt) func() {
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		cs.refs--
+		if cs.evicted && cs.refs == 0 {
+			cs.st.Close()
+		}
+	}
+}
+
+// isConnErr tell if the connection of the statements is lost
+func isConnErr(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone)
+}
+
+// stmtQueryer run a prepared statement, the query is ignored
+type stmtQueryer struct {
+	st *sqlx.Stmt
+}
+
+func (s stmtQueryer) QueryContext(ctx context.Context, _ string, args ...any) (*sql.Rows, error) {
+	return s.st.QueryContext(ctx, args...)
+}
+
+func (s stmtQueryer) QueryxContext(ctx context.Context, _ string, args ...any) (*sqlx.Rows, error) {
+	return s.st.QueryxContext(ctx, args...)
+}
+
+func (s stmtQueryer) QueryRowxContext(ctx context.Context, _ string, args ...any) *sqlx.Row {
+	return s.st.QueryRowxContext(ctx, args...)
+}
+
+func (s stmtQueryer) ExecContext(ctx context.Context, _ string, args ...any) (sql.Result, error) {
+	return s.st.ExecContext(ctx, args...)
+}
+
+// uncached_ops are the statements of the transaction itself
+// (savepoints, SET TRANSACTION...) run without the cache
+var uncached_ops = map[string]bool{
+	"Begin":    true,
+	"Commit":   true,
+	"Rollback": true,
+}
+
+// prepared renvoi le statement of query from the cache or e.q without cache
+// in a Tx only a statement already in the cache is used, rebound with
+// Stmtx on the connection of the Tx, to not take an other connection
+func (e env) prepared(ctx context.Context, op string, query string) (queryExecer, func(), error) {
+	if e.stmts == nil || uncached_ops[op] {
+		return e.q, func() {}, nil
+	}
+	tx, ok := e.q.(*sqlx.Tx)
+	if !ok {
+		st, release, err := e.stmts.get(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		return stmtQueryer{st}, release, nil
+	}
+	st, release, ok := e.stmts.lookup(query)
+	if !ok {
+		return e.q, func() {}, nil
+	}
+	txSt := tx.StmtxContext(ctx, st)
+	return stmtQueryer{txSt}, func() {
+		txSt.Close()
+		release()
+	}, nil
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestStmtCache(t *testing.T) {
+	db, f := openFake(t)
+	f.result = oneRow("n", int64(1))
+	x := WrapDB(context.Background(), db)
+	x.CacheStatements(2)
+
+	n := 0
+	for range 3 {
+		if err := x.Get(&n, "select n from a where id=$1", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := x.Exec("update b set n=1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.prepared) != 2 {
+		t.Errorf("prepared %v", f.prepared)
+	}
+	st := x.Stmts.Stats()
+	if st.Hits != 2 || st.Misses != 2 || st.Len != 2 || st.Evictions != 0 {
+		t.Errorf("stats %+v", st)
+	}
+
+	// query ne passe pas par le cache
+	rows, err := x.Query("select n from c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if x.Stmts.Stats().Misses != 2 {
+		t.Errorf("Query en cache")
+	}
+
+	// le plus ancien est fermé
+	x.Exec("update d set n=1")
+	st = x.Stmts.Stats()
+	if st.Evictions != 1 || st.Len != 2 || f.closed != 1 {
+		t.Errorf("eviction %+v closed %d", st, f.closed)
+	}
+
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Stmts != x.Stmts {
+		t.Fatalf("Tx sans le cache du DB")
+	}
+	if _, err := tx.Exec("update d set n=1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if x.Stmts.Stats().Hits != 3 {
+		t.Errorf("Tx hit %+v", x.Stmts.Stats())
+	}
+
+	// connexion perdue, le cache est vidé
+	f.result = func(string, []any) fakeRes { return fakeRes{err: driver.ErrBadConn} }
+	if _, err := x.Exec("update d set n=1"); err == nil {
+		t.Fatal("attend ErrBadConn")
+	}
+	if x.Stmts.Stats().Len != 0 {
+		t.Errorf("cache non vidé %+v", x.Stmts.Stats())
+	}
+	if err := x.Stmts.Clear(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStmtCacheConn(t *testing.T) {
+	db, f := openFake(t)
+	f.result = oneRow("n", int64(1))
+	x := WrapDB(context.Background(), db)
+	x.CacheStatements(10)
+	conn, err := x.Conn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Stmts == nil || conn.Stmts == x.Stmts {
+		t.Fatalf("Conn attend son propre cache")
+	}
+	n := 0
+	for range 2 {
+		if err := conn.Get(&n, "select 1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = conn.InTx(func(tx *Tx) error {
+		return tx.Get(&n, "select 1")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := conn.Stmts.Stats(); st.Hits != 2 || st.Misses != 1 {
+		t.Errorf("stats %+v", st)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if conn.Stmts.Stats().Len != 0 || f.closed == 0 {
+		t.Errorf("statements non fermés avec la Conn")
+	}
+}
+
+func TestStmtCacheTx(t *testing.T) {
+	db, f := openFake(t)
+	f.result = func(string, []any) fakeRes { return fakeRes{affected: 1} }
+	db.SetMaxOpenConns(1)
+	x := WrapDB(context.Background(), db)
+	x.Timeout = time.Second
+	x.CacheStatements(10)
+
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pas de prepare sur une autre connexion du pool
+	if _, err := tx.Exec("update t set n=1"); err != nil {
+		t.Fatal(err)
+	}
+	sp, err := tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	sp, err = tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if st := x.Stmts.Stats(); st.Len != 0 || len(f.prepared) != 0 {
+		t.Errorf("cache %+v prepared %v", st, f.prepared)
+	}
+}
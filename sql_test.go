@@ -17,7 +17,7 @@ func Test_insertSt(t *testing.T) {
 	fs["ok"] = "coral"
 	fs["yes"] = "no"
 	fs["raw"] = Raw("now()")
-	q, args := insertSt(DB_PG, "mytable", fs)
+	q, args, _ := insertSt(DB_PG, "mytable", fs)
 	if q != "INSERT INTO mytable (ok, raw, yes) VALUES ($1, now(), $2)" {
 		t.Error(q)
 		t.Error(args)
@@ -29,7 +29,7 @@ func Test_updateSt(t *testing.T) {
 	fs["ok"] = "coral"
 	fs["yes"] = "no"
 	fs["raw"] = Raw("now()")
-	q, args := updateSt(DB_PG, "mytable", fs, "ok=$1", "ok")
+	q, args, _ := updateSt(DB_PG, "mytable", fs, "ok=$1", "ok")
 	if q != "UPDATE mytable SET ok=$2, raw=now(), yes=$3 WHERE ok=$1" {
 		t.Error(q)
 		t.Error(args)
@@ -40,7 +40,7 @@ func Test_insertStMssql(t *testing.T) {
 	fs := map[string]any{}
 	fs["ok"] = "coral"
 	fs["yes"] = "no"
-	q, _ := insertSt(DB_MSSQL, "mytable", fs)
+	q, _, _ := insertSt(DB_MSSQL, "mytable", fs)
 	if q != "INSERT INTO mytable (ok, yes) VALUES (@p1, @p2)" {
 		log.Fatal(q)
 	}
@@ -50,7 +50,7 @@ func Test_updateStMssql(t *testing.T) {
 	fs := map[string]any{}
 	fs["ok"] = "coral"
 	fs["yes"] = "no"
-	q, _ := updateSt(DB_MSSQL, "mytable", fs, "ok=@p1", "ok")
+	q, _, _ := updateSt(DB_MSSQL, "mytable", fs, "ok=@p1", "ok")
 	if q != "UPDATE mytable SET ok=@p2, yes=@p3 WHERE ok=@p1" {
 		log.Fatal(q)
 	}
@@ -60,7 +60,7 @@ func Test_insertStAccess(t *testing.T) {
 	fs := map[string]any{}
 	fs["ok"] = "coral"
 	fs["yes"] = "no"
-	q, _ := insertSt(DB_ACCESS, "mytable", fs)
+	q, _, _ := insertSt(DB_ACCESS, "mytable", fs)
 	if q != "INSERT INTO mytable (ok, yes) VALUES (?, ?)" {
 		log.Fatalf("insert access : %s", q)
 	}
@@ -70,7 +70,7 @@ func Test_updateStAccess(t *testing.T) {
 	fs := map[string]any{}
 	fs["ok"] = "coral"
 	fs["yes"] = "no"
-	q, _ := updateSt(DB_ACCESS, "mytable", fs, "ok=?", "ok")
+	q, _, _ := updateSt(DB_ACCESS, "mytable", fs, "ok=?", "ok")
 	if q != "UPDATE mytable SET ok=?, yes=? WHERE ok=?" {
 		log.Fatalf("update access : %s", q)
 	}
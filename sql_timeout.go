@@ -0,0 +1,94 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrTimeout is wrapped in the error of a statement longer than Timeout
+// or canceled by the postgres statement_timeout
+var ErrTimeout = errors.New("sqlo: timeout")
+
+// IsTimeout tell if err is a timeout of a statement
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// withTimeout add the Timeout to ctx, not for Begin Commit Rollback
+// (query is empty) as the ctx of Begin is the one of the whole transaction
+// or if it's already in ctx (timeoutInCtx)
+func (e env) withTimeout(ctx context.Context, query string) (context.Context, context.CancelFunc) {
+	if e.timeout <= 0 || query == "" || e.timeoutInCtx {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, e.timeout)
+}
+
+// isTimeout: the deadline of ctx or postgres query_canceled 57014
+func isTimeout(ctx context.Context, err error) bool {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// timeoutErr log the statement and wrap err with ErrTimeout
+func (e env) timeoutErr(query string, args []any, err error) error {
+	after := ""
+	if e.timeout > 0 { // else the deadline of the ctx of the caller
+		after = " after " + e.timeout.String()
+	}
+	if e.logger != nil {
+		e.logger.Printf("sqlo timeout%s: %s", after, sql_fake(e.dbType, query, args...))
+	}
+	return fmt.Errorf("%w%s: %w", ErrTimeout, after, err)
+}
+
+// rowsQueryer is implemented by DB Tx Conn to give the cancel of the
+// Timeout of Query, called by the helpers when the rows are read
+type rowsQueryer interface {
+	queryRows(query string, args ...any) (*sqlx.Rows, context.CancelFunc, error)
+}
+
+// openRows is x.Query with the cancel of its Timeout
+func openRows(x Selecter, query string, args ...any) (*sqlx.Rows, context.CancelFunc, error) {
+	if q, ok := x.(rowsQueryer); ok {
+		return q.queryRows(query, args...)
+	}
+	rows, err := x.Query(query, args...)
+	return rows, func() {}, err
+}
+
+// queryRows run the Query op, the rows are read by the caller
+// with timeout, Timeout is the deadline of the query and of the reading
+// of the rows, cancel is to call after them, run doesn't add an other one
+// without, the Timeout is not applied, as the rows outlive the call
+func (e env) queryRows(query string, args []any, timeout bool) (*sqlx.Rows, context.CancelFunc, error) {
+	cancel := context.CancelFunc(func() {})
+	if timeout {
+		e.ctx, cancel = e.withTimeout(e.ctx, query)
+		e.timeoutInCtx = true
+	} else {
+		e.timeout = 0
+	}
+	e.stmts = nil // the rows would outlive the statement
+	var rows *sqlx.Rows
+	_, err := e.stmt("Query", query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		var err error
+		rows, err = q.QueryxContext(ctx, query, args...)
+		return nil, err
+	})
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return rows, cancel, nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestTimeout(t *testing.T) {
+	db, f := openFake(t)
+	f.result = func(q string, _ []any) fakeRes {
+		return fakeRes{block: strings.HasPrefix(q, "select pg_sleep")}
+	}
+	buf := &bytes.Buffer{}
+	x := WrapDB(context.Background(), db)
+	x.Timeout = 10 * time.Millisecond
+	x.StatementTimeout = true
+
+	_, err := x.Exec("select pg_sleep($1)", 10)
+	if !IsTimeout(err) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("attend timeout reçoit %v", err)
+	}
+	if _, err := x.Exec("update t"); err != nil {
+		t.Errorf("attend nil reçoit %v", err)
+	}
+
+	x.Logger = log.New(buf, "", 0)
+	tx, err := x.WithTimeout(20 * time.Millisecond).Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Timeout != 20*time.Millisecond {
+		t.Errorf("Timeout non propagé %s", tx.Timeout)
+	}
+	_, err = tx.Exec("select pg_sleep($1)", 10)
+	if !IsTimeout(err) {
+		t.Errorf("attend timeout reçoit %v", err)
+	}
+	tx.Rollback()
+	if !strings.Contains(buf.String(), "sqlo timeout after 20ms: select pg_sleep(10)") {
+		t.Errorf("log du timeout: %s", buf.String())
+	}
+	if q := f.Queries()[3]; q != "SET LOCAL statement_timeout = 20" {
+		t.Errorf("attend SET LOCAL reçoit %s", q)
+	}
+
+	f.result = func(string, []any) fakeRes {
+		return fakeRes{err: &pq.Error{Code: "57014"}}
+	}
+	if _, err := x.Exec("update t"); !IsTimeout(err) {
+		t.Errorf("57014 attend timeout reçoit %v", err)
+	}
+	f.result = func(string, []any) fakeRes {
+		return fakeRes{err: errors.New("boom")}
+	}
+	if _, err := x.Exec("update t"); IsTimeout(err) {
+		t.Errorf("boom n'est pas un timeout")
+	}
+}
+
+func TestTimeoutQuery(t *testing.T) {
+	db, f := openFake(t)
+	f.result = func(string, []any) fakeRes { return fakeRes{block: true} }
+	x := WrapDB(context.Background(), db)
+	x.Timeout = 10 * time.Millisecond
+
+	if _, err := x.SelectMaps("select * from t"); !IsTimeout(err) {
+		t.Errorf("SelectMaps attend timeout reçoit %v", err)
+	}
+	for _, err := range Rows[int64](x, "select n from t") {
+		if !IsTimeout(err) {
+			t.Errorf("Rows attend timeout reçoit %v", err)
+		}
+	}
+	// Query n'a pas de Timeout, seulement le ctx de l'appelant
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := x.QueryContext(ctx, "select * from t")
+	if !IsTimeout(err) || strings.Contains(err.Error(), "after") {
+		t.Errorf("QueryContext attend timeout sans durée reçoit %v", err)
+	}
+
+	// les lignes restent lisibles après le retour, en attendant avant Next
+	x.Timeout = time.Second
+	f.result = func(string, []any) fakeRes {
+		return fakeRes{cols: []string{"n"}, rows: [][]driver.Value{{int64(1)}, {int64(2)}}}
+	}
+	rows, err := x.Query("select n from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	time.Sleep(20 * time.Millisecond)
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	if rows.Err() != nil || n != 2 {
+		t.Errorf("Query lignes %d %v", n, rows.Err())
+	}
+	n = 0
+	for _, err := range Rows[int64](x, "select n from t") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		n++
+	}
+	if n != 2 {
+		t.Errorf("Rows lignes %d", n)
+	}
+}
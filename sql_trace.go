@@ -0,0 +1,69 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Tracer open a span for each call of the wrappers (Select, Get, Exec,
+// InsertMap, UpdateMap, Begin, Commit, Rollback...)
+// it's a small interface to plug opentelemetry or anything else
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span receive the attributes of the call and is ended after it
+// attributes are db.system, db.statement, db.rows_affected and error
+type Span interface {
+	SetAttribute(key string, value any)
+	End()
+}
+
+// dbSystem renvoi le nom du type de base pour db.system
+func dbSystem(dbType int) string {
+	switch dbType {
+	case DB_ACCESS:
+		return "access"
+	case DB_MSSQL:
+		return "mssql"
+	default:
+		return "postgresql"
+	}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(string, any) {}
+func (nopSpan) End()                     {}
+
+// startSpan open the span "sqlo.<op>" with db.system and db.statement
+// the statement is rendered with sql_fake if fake is true
+func startSpan(ctx context.Context, t Tracer, dbType int, fake bool, op string, query string, args []any) (context.Context, Span) {
+	if t == nil {
+		return ctx, nopSpan{}
+	}
+	ctx, span := t.Start(ctx, "sqlo."+op)
+	span.SetAttribute("db.system", dbSystem(dbType))
+	if query != "" {
+		if fake {
+			query = sql_fake(dbType, query, args...)
+		}
+		span.SetAttribute("db.statement", query)
+	}
+	return ctx, span
+}
+
+// endSpan add rows affected and error before ending the span
+func endSpan(span Span, res sql.Result, err error) {
+	if res != nil {
+		if n, e := res.RowsAffected(); e == nil {
+			span.SetAttribute("db.rows_affected", n)
+		}
+	}
+	if err != nil {
+		span.SetAttribute("error", err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recorder is an in memory Tracer
+type recorder struct {
+	mu    sync.Mutex
+	spans []*recSpan
+}
+
+type recSpan struct {
+	name  string
+	attrs map[string]any
+	ended bool
+}
+
+func (r *recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &recSpan{name: name, attrs: map[string]any{}}
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+	return ctx, s
+}
+
+func (s *recSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *recSpan) End()                               { s.ended = true }
+
+func TestTracer(t *testing.T) {
+	db, f := openFake(t)
+	f.result = oneRow("n", int64(1))
+	rec := &recorder{}
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_MSSQL
+	x.Tracer = rec
+	x.TraceFake = true
+
+	n := 0
+	if err := x.Get(&n, "select @p1", 1); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.InsertMap("t", map[string]any{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sqlo.Get", "sqlo.Begin", "sqlo.InsertMap", "sqlo.Commit"}
+	if len(rec.spans) != len(want) {
+		t.Fatalf("spans %d attend %d", len(rec.spans), len(want))
+	}
+	for i, s := range rec.spans {
+		if s.name != want[i] || !s.ended {
+			t.Errorf("span %d attend %s reçoit %s ended=%v", i, want[i], s.name, s.ended)
+		}
+		if s.attrs["db.system"] != "mssql" {
+			t.Errorf("%s db.system: %v", s.name, s.attrs["db.system"])
+		}
+	}
+	if st := rec.spans[0].attrs["db.statement"]; st != "select 1" {
+		t.Errorf("db.statement attend select 1 reçoit %v", st)
+	}
+	if st := rec.spans[2].attrs["db.statement"]; st != "INSERT INTO t (a) VALUES ('b')" {
+		t.Errorf("db.statement reçoit %v", st)
+	}
+	if n := rec.spans[2].attrs["db.rows_affected"]; n != int64(1) {
+		t.Errorf("db.rows_affected attend 1 reçoit %v", n)
+	}
+
+	f.result = func(string, []any) fakeRes { return fakeRes{err: errors.New("boom")} }
+	x.TraceFake = false
+	x.Exec("delete from t where a=@p1", "b")
+	last := rec.spans[len(rec.spans)-1]
+	if last.attrs["error"] != "boom" {
+		t.Errorf("error attend boom reçoit %v", last.attrs["error"])
+	}
+	if last.attrs["db.statement"] != "delete from t where a=@p1" {
+		t.Errorf("db.statement reçoit %v", last.attrs["db.statement"])
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"errors"
+	"maps"
+)
+
+// ErrStaleRow is returned by UpdateMapVersion when no row
+// has the expected version, it was modified or deleted meanwhile
+var ErrStaleRow = errors.New("stale row")
+
+// versionSt add version=expected to where and set version in m
+// to version+1, or to m[version] if given (updated_at...)
+// where must be already bound to the placeholders of dbType
+func versionSt(dbType int, m map[string]any, version string, expected any, where string, where_vals []any) (map[string]any, string, []any, error) {
+	col, err := QuoteIdent(dbType, version)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	m = maps.Clone(m)
+	if _, ok := m[version]; !ok {
+		m[version] = Raw(col + "+1")
+	}
+	cond := col + "=" + formatPlaceholder(placeholderStyle(dbType), len(where_vals)+1)
+	if where != "" {
+		cond = "(" + where + ") AND " + cond
+	}
+	where_vals = append(where_vals[:len(where_vals):len(where_vals)], expected)
+	return m, cond, where_vals, nil
+}
+
+// checkStale renvoi ErrStaleRow if res didn't affect any row
+func checkStale(res sql.Result, err error) (sql.Result, error) {
+	if err != nil {
+		return res, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return res, err
+	}
+	if n == 0 {
+		return res, ErrStaleRow
+	}
+	return res, nil
+}
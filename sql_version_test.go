@@ -0,0 +1,78 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateMapVersion(t *testing.T) {
+	tbl := []struct {
+		name     string
+		dbType   int
+		portable bool
+		where    string
+		query    string
+		args     []any
+	}{
+		{"pg", DB_PG, false, "id=$1",
+			`UPDATE t SET a=$3, version=version+1 WHERE (id=$1) AND version=$2`, []any{int64(1), int64(7), "b"}},
+		{"pg portable", DB_PG, true, "id=?",
+			`UPDATE t SET a=$3, version=version+1 WHERE (id=$1) AND version=$2`, []any{int64(1), int64(7), "b"}},
+		{"mssql", DB_MSSQL, false, "id=@p1",
+			`UPDATE t SET a=@p3, version=version+1 WHERE (id=@p1) AND version=@p2`, []any{int64(1), int64(7), "b"}},
+		{"access", DB_ACCESS, false, "id=?",
+			`UPDATE t SET a=?, version=version+1 WHERE (id=?) AND version=?`, []any{"b", int64(1), int64(7)}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			db, f := openFake(t)
+			f.result = func(string, []any) fakeRes { return fakeRes{affected: 1} }
+			x := WrapDB(context.Background(), db)
+			x.DbType = tt.dbType
+			x.Portable = tt.portable
+			m := map[string]any{"a": "b"}
+			if _, err := x.UpdateMapVersion("t", m, "version", int64(7), tt.where, int64(1)); err != nil {
+				t.Fatal(err)
+			}
+			if q := f.Queries()[0]; q != tt.query {
+				t.Errorf("attend %s\nreçoit %s", tt.query, q)
+			}
+			if !reflect.DeepEqual(f.args[0], tt.args) {
+				t.Errorf("args %v", f.args[0])
+			}
+			if len(m) != 1 {
+				t.Errorf("m modifié %v", m)
+			}
+		})
+	}
+}
+
+func TestUpdateMapVersionStale(t *testing.T) {
+	db, f := openFake(t)
+	f.result = func(string, []any) fakeRes { return fakeRes{affected: 0} }
+	x := WrapDB(context.Background(), db)
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	m := map[string]any{"a": "b", "updated_at": "2025-01-02"}
+	_, err = tx.UpdateMapVersion("t", m, "updated_at", "2025-01-01", "id=$1", 1)
+	if !errors.Is(err, ErrStaleRow) {
+		t.Errorf("attend ErrStaleRow reçoit %v", err)
+	}
+	want := `UPDATE t SET a=$3, updated_at=$4 WHERE (id=$1) AND updated_at=$2`
+	if q := f.Queries()[1]; q != want {
+		t.Errorf("attend %s\nreçoit %s", want, q)
+	}
+
+	_, err = tx.UpdateMapVersion("t", m, "version;", 1, "id=$1", 1)
+	if !errors.Is(err, ErrInvalidIdent) {
+		t.Errorf("attend ErrInvalidIdent reçoit %v", err)
+	}
+}
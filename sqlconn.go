@@ -7,17 +7,43 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Conn struct {
-	Ctx    context.Context
-	conn   *sqlx.Conn
-	Logger *log.Logger
-	DbType int
+	Ctx       context.Context
+	conn      *sqlx.Conn
+	Logger    *log.Logger
+	DbType    int
+	Tracer    Tracer
+	TraceFake bool // db.statement rendered with sql_fake
+	Metrics   *Metrics
+	Leaks     *LeakDetector
+	Timeout   time.Duration // of each statement, 0 for none
+	// StatementTimeout add SET LOCAL statement_timeout at the begin
+	// of the transactions with postgres, so the server cancel too
+	StatementTimeout bool
+	// ExpandSlices develop a slice argument in as many placeholders
+	// "id in ($1)", []int{1, 2} -> "id in ($1,$2)"
+	ExpandSlices bool
+	// Portable: Select Get Exec Query are written with ? placeholders
+	// rebound to $n or @pN according to DbType, ?? for a literal ?
+	Portable bool
+	// RedactArgs keep the values of the args out of QueryError
+	RedactArgs bool
+	// SafeUpdates refuse UpdateMap and Delete with a where empty
+	// or always true (1=1...), ErrUnsafeWhere
+	SafeUpdates bool
+	// Stmts cache the prepared statements, see CacheStatements
+	Stmts *StmtCache
+
+	release func() // for Leaks
 }
 
+// NewConn take a connection from db without the settings of a DB
+// it's not recorded by a LeakDetector, use DB.Conn for that
 func NewConn(ctx context.Context, db *sqlx.DB) (*Conn, error) {
 	conn, err := db.Connx(ctx)
 	if err != nil {
@@ -36,7 +62,19 @@ func WrapConn(ctx context.Context, conn *sqlx.Conn) *Conn {
 	}
 }
 
+// CacheStatements keep the last size prepared statements in x.Stmts
+// they are closed with the Conn
+func (x *Conn) CacheStatements(size int) {
+	x.Stmts = newStmtCache(x.conn, size)
+}
+
 func (x *Conn) Close() error {
+	if x.release != nil {
+		x.release()
+	}
+	if x.Stmts != nil {
+		x.Stmts.Clear()
+	}
 	err := x.conn.Close()
 	if err != nil {
 		return fmt.Errorf("Close conn: %v", err)
@@ -44,37 +82,146 @@ func (x *Conn) Close() error {
 	return nil
 }
 
+func (x *Conn) env() env {
+	return env{
+		ctx:       x.Ctx,
+		logger:    x.Logger,
+		dbType:    x.DbType,
+		tracer:    x.Tracer,
+		traceFake: x.TraceFake,
+		metrics:   x.Metrics,
+		leaks:     x.Leaks,
+		timeout:   x.Timeout,
+		stTimeout: x.StatementTimeout,
+
+		expandSlices: x.ExpandSlices,
+		portable:     x.Portable,
+		redactArgs:   x.RedactArgs,
+		safeUpdates:  x.SafeUpdates,
+
+		q:     x.conn,
+		stmts: x.Stmts,
+	}
+}
+
+// WithTimeout renvoi une copie avec le Timeout d
+func (x *Conn) WithTimeout(d time.Duration) *Conn {
+	c := *x
+	c.Timeout = d
+	return &c
+}
+
+// WithContext renvoi une copie qui utilise ctx
+// with the same logger, DbType, hooks and the same connection
+func (x *Conn) WithContext(ctx context.Context) *Conn {
+	c := *x
+	c.Ctx = ctx
+	return &c
+}
+
 func (x *Conn) Begin() (*Tx, error) {
-	var err error
-	tx, err := x.conn.BeginTxx(x.Ctx, nil)
+	return x.BeginWith(TxOptions{})
+}
+
+// BeginWith open a transaction with isolation level, read only or deferrable
+func (x *Conn) BeginWith(opts TxOptions) (*Tx, error) {
+	return x.env().beginTx(opts, x.conn.BeginTxx)
+}
+
+// InTx run fn in a transaction
+// commit if fn return nil, rollback if fn return an error or panic
+func (x *Conn) InTx(fn func(*Tx) error) error {
+	tx, err := x.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("conn Begin: %w", err)
+		return err
 	}
-	return &Tx{
-		tx:     tx,
-		Logger: x.Logger,
-		Ctx:    x.Ctx,
-	}, nil
+	return inTx(tx, fn)
 }
 
-func (x *Conn) log(query string, args ...any) {
-	if x.Logger == nil {
-		return
-	}
-	x.Logger.Println(sql_fake(x.DbType, query, args...))
+// InTxRetry is like InTx but run fn again in a new transaction
+// on serialization failure or deadlock, according to r
+func (x *Conn) InTxRetry(r Retry, fn func(*Tx) error) error {
+	return retryTx(x.Ctx, r, x.Begin, fn)
 }
 
 func (x *Conn) Select(dest any, query string, args ...any) error {
-	x.log(query, args...)
-	if x.conn == nil {
-		return fmt.Errorf("sxc: %T", x.conn)
-	}
-	return sqlx.SelectContext(x.Ctx, x.conn, dest, query, args...)
+	return x.sel(x.Ctx, "Select", dest, query, args...)
+}
+
+func (x *Conn) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return x.sel(ctx, "Select", dest, query, args...)
+}
+
+func (x *Conn) sel(ctx context.Context, op string, dest any, query string, args ...any) error {
+	_, err := x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		if x.conn == nil {
+			return nil, fmt.Errorf("sxc: %T", x.conn)
+		}
+		return nil, sqlx.SelectContext(ctx, q, dest, query, args...)
+	})
+	return err
 }
 
 func (x *Conn) Get(dest any, query string, args ...any) error {
-	x.log(query, args...)
-	return sqlx.GetContext(x.Ctx, x.conn, dest, query, args...)
+	return x.get(x.Ctx, "Get", dest, query, args...)
+}
+
+func (x *Conn) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return x.get(ctx, "Get", dest, query, args...)
+}
+
+func (x *Conn) get(ctx context.Context, op string, dest any, query string, args ...any) error {
+	_, err := x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		return nil, sqlx.GetContext(ctx, q, dest, query, args...)
+	})
+	return err
+}
+
+// Query renvoi les lignes de la requête, à fermer par l'appelant
+// Timeout is not applied as the rows outlive the call
+// Rows[T] SelectMaps GetMap SelectTable apply it to the reading of the rows
+func (x *Conn) Query(query string, args ...any) (*sqlx.Rows, error) {
+	return x.QueryContext(x.Ctx, query, args...)
+}
+
+func (x *Conn) QueryContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	rows, _, err := x.env().with(ctx).queryRows(query, args, false)
+	return rows, err
+}
+
+// queryRows is Query with Timeout, cancel is to call after the rows
+func (x *Conn) queryRows(query string, args ...any) (*sqlx.Rows, context.CancelFunc, error) {
+	return x.env().queryRows(query, args, true)
+}
+
+func (x *Conn) queryErr(op string, query string, args []any, err error) error {
+	return x.env().queryErr(op, query, args, err)
+}
+
+// SelectMaps renvoi les lignes en map, for columns unknown before
+// the []byte of text columns are converted to string
+func (x *Conn) SelectMaps(query string, args ...any) ([]map[string]any, error) {
+	return selectMaps(x, query, args...)
+}
+
+// GetMap renvoi la première ligne en map, sql.ErrNoRows if none
+func (x *Conn) GetMap(query string, args ...any) (map[string]any, error) {
+	return getMap(x, query, args...)
+}
+
+// SelectTable is like SelectMaps but keep the order and the type of the columns
+func (x *Conn) SelectTable(query string, args ...any) (*Table, error) {
+	return selectTable(x, query, args...)
+}
+
+// Exists tell if table has a row matching w (can be nil)
+func (x *Conn) Exists(table string, w *Where) (bool, error) {
+	return exists(x, table, w)
+}
+
+// Count renvoi le nombre de lignes de table matching w (can be nil)
+func (x *Conn) Count(table string, w *Where) (int64, error) {
+	return count(x, table, w)
 }
 
 func (x *Conn) MustExec(query string, args ...any) sql.Result {
@@ -85,13 +232,56 @@ func (x *Conn) MustExec(query string, args ...any) sql.Result {
 	return res
 }
 func (x *Conn) Exec(query string, args ...any) (sql.Result, error) {
-	x.log(query, args...)
-	return x.conn.ExecContext(x.Ctx, query, args...)
+	return x.exec(x.Ctx, "Exec", query, args...)
+}
+
+func (x *Conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return x.exec(ctx, "Exec", query, args...)
+}
+
+func (x *Conn) exec(ctx context.Context, op string, query string, args ...any) (sql.Result, error) {
+	return x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		return q.ExecContext(ctx, query, args...)
+	})
+}
+
+// NamedExec is like Exec with :name taken from arg (struct or map)
+func (x *Conn) NamedExec(query string, arg any) (sql.Result, error) {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("NamedExec: %w", err)
+	}
+	return x.exec(x.Ctx, "NamedExec", s, args...)
+}
+
+// NamedSelect is like Select with :name taken from arg (struct or map)
+func (x *Conn) NamedSelect(dest any, query string, arg any) error {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return fmt.Errorf("NamedSelect: %w", err)
+	}
+	return x.sel(x.Ctx, "NamedSelect", dest, s, args...)
+}
+
+// NamedGet is like Get with :name taken from arg (struct or map)
+func (x *Conn) NamedGet(dest any, query string, arg any) error {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return fmt.Errorf("NamedGet: %w", err)
+	}
+	return x.get(x.Ctx, "NamedGet", dest, s, args...)
 }
 
 func (x *Conn) InsertMap(table string, m map[string]any) (sql.Result, error) {
-	s, values := insertSt(x.DbType, table, m)
-	res, err := x.Exec(s, values...)
+	return x.InsertMapContext(x.Ctx, table, m)
+}
+
+func (x *Conn) InsertMapContext(ctx context.Context, table string, m map[string]any) (sql.Result, error) {
+	s, values, err := insertSt(x.DbType, table, m)
+	if err != nil {
+		return nil, fmt.Errorf("InsertMap: %w", err)
+	}
+	res, err := x.exec(ctx, "InsertMap", s, values...)
 	return res, err
 }
 
@@ -100,14 +290,35 @@ func (x *Conn) InsertMap(table string, m map[string]any) (sql.Result, error) {
 // dest must be a pointer to destination
 // returning is the name(s) of the field(s)
 func (x *Conn) InsertMapReturning(dest any, returning string, table string, m map[string]any) error {
-	s, values := insertSt(x.DbType, table, m)
+	return x.InsertMapReturningContext(x.Ctx, dest, returning, table, m)
+}
+
+func (x *Conn) InsertMapReturningContext(ctx context.Context, dest any, returning string, table string, m map[string]any) error {
+	s, values, err := insertSt(x.DbType, table, m)
+	if err != nil {
+		return fmt.Errorf("InsertMapReturning: %w", err)
+	}
 	s += " returning " + returning
-	return x.Get(dest, s, values...)
+	return x.get(ctx, "InsertMapReturning", dest, s, values...)
 }
 
 func (x *Conn) UpdateMap(table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
-	s, values := updateSt(x.DbType, table, m, where, where_vals...)
-	res, err := x.Exec(s, values...)
+	return x.UpdateMapContext(x.Ctx, table, m, where, where_vals...)
+}
+
+func (x *Conn) UpdateMapContext(ctx context.Context, table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	res, err := x.exec(ctx, "UpdateMap", s, values...)
 
 	return res, err
 }
@@ -117,7 +328,94 @@ func (x *Conn) UpdateMap(table string, m map[string]any, where string, where_val
 // dest must be a pointer to destination
 // returning is the name(s) of the field(s)
 func (x *Conn) UpdateMapReturning(dest any, returning string, table string, m map[string]any, where string, where_vals ...any) error {
-	s, values := updateSt(x.DbType, table, m, where, where_vals...)
+	return x.UpdateMapReturningContext(x.Ctx, dest, returning, table, m, where, where_vals...)
+}
+
+func (x *Conn) UpdateMapReturningContext(ctx context.Context, dest any, returning string, table string, m map[string]any, where string, where_vals ...any) error {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
 	s += " returning " + returning
-	return x.Get(dest, s, values...)
+	return x.get(ctx, "UpdateMapReturning", dest, s, values...)
+}
+
+// UpdateMapOne is like UpdateMap but return ErrRowsAffected
+// if it didn't update exactly one row
+func (x *Conn) UpdateMapOne(table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	return x.UpdateMapExpect(1, table, m, where, where_vals...)
+}
+
+// UpdateMapExpect is like UpdateMap but return ErrRowsAffected
+// if it didn't update n rows
+func (x *Conn) UpdateMapExpect(n int64, table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	res, err := x.UpdateMap(table, m, where, where_vals...)
+	return expectRows(n, res, err)
+}
+
+// Delete the rows of table matching where
+func (x *Conn) Delete(table string, where string, where_vals ...any) (sql.Result, error) {
+	return x.DeleteContext(x.Ctx, table, where, where_vals...)
+}
+
+func (x *Conn) DeleteContext(ctx context.Context, table string, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	s, err := deleteSt(x.DbType, table, where)
+	if err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	return x.exec(ctx, "Delete", s, where_vals...)
+}
+
+// DeleteOne is like Delete but return ErrRowsAffected
+// if it didn't delete exactly one row
+func (x *Conn) DeleteOne(table string, where string, where_vals ...any) (sql.Result, error) {
+	return x.DeleteExpect(1, table, where, where_vals...)
+}
+
+// DeleteExpect is like Delete but return ErrRowsAffected
+// if it didn't delete n rows
+func (x *Conn) DeleteExpect(n int64, table string, where string, where_vals ...any) (sql.Result, error) {
+	res, err := x.Delete(table, where, where_vals...)
+	return expectRows(n, res, err)
+}
+
+// UpdateMapVersion is UpdateMap with optimistic locking
+// the row must have expected in the column version
+// version is set to version+1, or to m[version] if given (updated_at...)
+// ErrStaleRow if no row matched
+func (x *Conn) UpdateMapVersion(table string, m map[string]any, version string, expected any, where string, where_vals ...any) (sql.Result, error) {
+	return x.UpdateMapVersionContext(x.Ctx, table, m, version, expected, where, where_vals...)
+}
+
+func (x *Conn) UpdateMapVersionContext(ctx context.Context, table string, m map[string]any, version string, expected any, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	m, where, where_vals, err = versionSt(x.DbType, m, version, expected, where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	return checkStale(x.exec(ctx, "UpdateMapVersion", s, values...))
 }
@@ -7,15 +7,37 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type DB struct {
-	Ctx    context.Context
-	db     *sqlx.DB
-	Logger *log.Logger
-	DbType int
+	Ctx       context.Context
+	db        *sqlx.DB
+	Logger    *log.Logger
+	DbType    int
+	Tracer    Tracer
+	TraceFake bool // db.statement rendered with sql_fake
+	Metrics   *Metrics
+	Leaks     *LeakDetector
+	Timeout   time.Duration // of each statement, 0 for none
+	// StatementTimeout add SET LOCAL statement_timeout at the begin
+	// of the transactions with postgres, so the server cancel too
+	StatementTimeout bool
+	// ExpandSlices develop a slice argument in as many placeholders
+	// "id in ($1)", []int{1, 2} -> "id in ($1,$2)"
+	ExpandSlices bool
+	// Portable: Select Get Exec Query are written with ? placeholders
+	// rebound to $n or @pN according to DbType, ?? for a literal ?
+	Portable bool
+	// RedactArgs keep the values of the args out of QueryError
+	RedactArgs bool
+	// SafeUpdates refuse UpdateMap and Delete with a where empty
+	// or always true (1=1...), ErrUnsafeWhere
+	SafeUpdates bool
+	// Stmts cache the prepared statements, see CacheStatements
+	Stmts *StmtCache
 }
 
 func WrapDB(ctx context.Context, db *sqlx.DB) *DB {
@@ -25,37 +47,163 @@ func WrapDB(ctx context.Context, db *sqlx.DB) *DB {
 	}
 }
 
-func (x *DB) Begin() (*Tx, error) {
-	var err error
-	tx, err := x.db.BeginTxx(x.Ctx, nil)
+// Conn take a connection from the pool with the settings of the DB
+func (x *DB) Conn() (*Conn, error) {
+	conn, err := NewConn(x.Ctx, x.db)
 	if err != nil {
-		return nil, fmt.Errorf("conn Begin: %w", err)
+		return nil, err
 	}
-	return &Tx{
-		tx:     tx,
-		Logger: x.Logger,
-		Ctx:    x.Ctx,
-	}, nil
+	x.env().setConn(conn)
+	return conn, nil
+}
+
+// CacheStatements keep the last size prepared statements in x.Stmts
+// the Conn from x.Conn have their own cache of the same size
+// x.Stmts.Clear() close them
+func (x *DB) CacheStatements(size int) {
+	x.Stmts = newStmtCache(x.db, size)
 }
 
-func (x *DB) log(query string, args ...any) {
-	if x.Logger == nil {
-		return
+func (x *DB) env() env {
+	return env{
+		ctx:       x.Ctx,
+		logger:    x.Logger,
+		dbType:    x.DbType,
+		tracer:    x.Tracer,
+		traceFake: x.TraceFake,
+		metrics:   x.Metrics,
+		leaks:     x.Leaks,
+		timeout:   x.Timeout,
+		stTimeout: x.StatementTimeout,
+
+		expandSlices: x.ExpandSlices,
+		portable:     x.Portable,
+		redactArgs:   x.RedactArgs,
+		safeUpdates:  x.SafeUpdates,
+
+		q:     x.db,
+		stmts: x.Stmts,
 	}
-	x.Logger.Println(sql_fake(x.DbType, query, args...))
 }
 
-func (x *DB) Select(dest any, query string, args ...any) error {
-	x.log(query, args...)
-	if x.db == nil {
-		return fmt.Errorf("sxc: %T", x.db)
+// WithTimeout renvoi une copie avec le Timeout d
+func (x *DB) WithTimeout(d time.Duration) *DB {
+	c := *x
+	c.Timeout = d
+	return &c
+}
+
+// WithContext renvoi une copie qui utilise ctx
+// with the same logger, DbType, hooks
+func (x *DB) WithContext(ctx context.Context) *DB {
+	c := *x
+	c.Ctx = ctx
+	return &c
+}
+
+func (x *DB) Begin() (*Tx, error) {
+	return x.BeginWith(TxOptions{})
+}
+
+// BeginWith open a transaction with isolation level, read only or deferrable
+func (x *DB) BeginWith(opts TxOptions) (*Tx, error) {
+	return x.env().beginTx(opts, x.db.BeginTxx)
+}
+
+// InTx run fn in a transaction
+// commit if fn return nil, rollback if fn return an error or panic
+func (x *DB) InTx(fn func(*Tx) error) error {
+	tx, err := x.Begin()
+	if err != nil {
+		return err
 	}
-	return sqlx.SelectContext(x.Ctx, x.db, dest, query, args...)
+	return inTx(tx, fn)
+}
+
+// InTxRetry is like InTx but run fn again in a new transaction
+// on serialization failure or deadlock, according to r
+func (x *DB) InTxRetry(r Retry, fn func(*Tx) error) error {
+	return retryTx(x.Ctx, r, x.Begin, fn)
+}
+
+func (x *DB) Select(dest any, query string, args ...any) error {
+	return x.sel(x.Ctx, "Select", dest, query, args...)
+}
+
+func (x *DB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return x.sel(ctx, "Select", dest, query, args...)
+}
+
+func (x *DB) sel(ctx context.Context, op string, dest any, query string, args ...any) error {
+	_, err := x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		if x.db == nil {
+			return nil, fmt.Errorf("sxc: %T", x.db)
+		}
+		return nil, sqlx.SelectContext(ctx, q, dest, query, args...)
+	})
+	return err
 }
 
 func (x *DB) Get(dest any, query string, args ...any) error {
-	x.log(query, args...)
-	return sqlx.GetContext(x.Ctx, x.db, dest, query, args...)
+	return x.get(x.Ctx, "Get", dest, query, args...)
+}
+
+func (x *DB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return x.get(ctx, "Get", dest, query, args...)
+}
+
+func (x *DB) get(ctx context.Context, op string, dest any, query string, args ...any) error {
+	_, err := x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		return nil, sqlx.GetContext(ctx, q, dest, query, args...)
+	})
+	return err
+}
+
+// Query renvoi les lignes de la requête, à fermer par l'appelant
+// Timeout is not applied as the rows outlive the call
+// Rows[T] SelectMaps GetMap SelectTable apply it to the reading of the rows
+func (x *DB) Query(query string, args ...any) (*sqlx.Rows, error) {
+	return x.QueryContext(x.Ctx, query, args...)
+}
+
+func (x *DB) QueryContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	rows, _, err := x.env().with(ctx).queryRows(query, args, false)
+	return rows, err
+}
+
+// queryRows is Query with Timeout, cancel is to call after the rows
+func (x *DB) queryRows(query string, args ...any) (*sqlx.Rows, context.CancelFunc, error) {
+	return x.env().queryRows(query, args, true)
+}
+
+func (x *DB) queryErr(op string, query string, args []any, err error) error {
+	return x.env().queryErr(op, query, args, err)
+}
+
+// SelectMaps renvoi les lignes en map, for columns unknown before
+// the []byte of text columns are converted to string
+func (x *DB) SelectMaps(query string, args ...any) ([]map[string]any, error) {
+	return selectMaps(x, query, args...)
+}
+
+// GetMap renvoi la première ligne en map, sql.ErrNoRows if none
+func (x *DB) GetMap(query string, args ...any) (map[string]any, error) {
+	return getMap(x, query, args...)
+}
+
+// SelectTable is like SelectMaps but keep the order and the type of the columns
+func (x *DB) SelectTable(query string, args ...any) (*Table, error) {
+	return selectTable(x, query, args...)
+}
+
+// Exists tell if table has a row matching w (can be nil)
+func (x *DB) Exists(table string, w *Where) (bool, error) {
+	return exists(x, table, w)
+}
+
+// Count renvoi le nombre de lignes de table matching w (can be nil)
+func (x *DB) Count(table string, w *Where) (int64, error) {
+	return count(x, table, w)
 }
 
 func (x *DB) MustExec(query string, args ...any) sql.Result {
@@ -66,13 +214,56 @@ func (x *DB) MustExec(query string, args ...any) sql.Result {
 	return res
 }
 func (x *DB) Exec(query string, args ...any) (sql.Result, error) {
-	x.log(query, args...)
-	return x.db.ExecContext(x.Ctx, query, args...)
+	return x.exec(x.Ctx, "Exec", query, args...)
+}
+
+func (x *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return x.exec(ctx, "Exec", query, args...)
+}
+
+func (x *DB) exec(ctx context.Context, op string, query string, args ...any) (sql.Result, error) {
+	return x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		return q.ExecContext(ctx, query, args...)
+	})
+}
+
+// NamedExec is like Exec with :name taken from arg (struct or map)
+func (x *DB) NamedExec(query string, arg any) (sql.Result, error) {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("NamedExec: %w", err)
+	}
+	return x.exec(x.Ctx, "NamedExec", s, args...)
+}
+
+// NamedSelect is like Select with :name taken from arg (struct or map)
+func (x *DB) NamedSelect(dest any, query string, arg any) error {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return fmt.Errorf("NamedSelect: %w", err)
+	}
+	return x.sel(x.Ctx, "NamedSelect", dest, s, args...)
+}
+
+// NamedGet is like Get with :name taken from arg (struct or map)
+func (x *DB) NamedGet(dest any, query string, arg any) error {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return fmt.Errorf("NamedGet: %w", err)
+	}
+	return x.get(x.Ctx, "NamedGet", dest, s, args...)
 }
 
 func (x *DB) InsertMap(table string, m map[string]any) (sql.Result, error) {
-	s, values := insertSt(x.DbType, table, m)
-	res, err := x.Exec(s, values...)
+	return x.InsertMapContext(x.Ctx, table, m)
+}
+
+func (x *DB) InsertMapContext(ctx context.Context, table string, m map[string]any) (sql.Result, error) {
+	s, values, err := insertSt(x.DbType, table, m)
+	if err != nil {
+		return nil, fmt.Errorf("InsertMap: %w", err)
+	}
+	res, err := x.exec(ctx, "InsertMap", s, values...)
 	return res, err
 }
 
@@ -81,14 +272,35 @@ func (x *DB) InsertMap(table string, m map[string]any) (sql.Result, error) {
 // dest must be a pointer to destination
 // returning is the name(s) of the field(s)
 func (x *DB) InsertMapReturning(dest any, returning string, table string, m map[string]any) error {
-	s, values := insertSt(x.DbType, table, m)
+	return x.InsertMapReturningContext(x.Ctx, dest, returning, table, m)
+}
+
+func (x *DB) InsertMapReturningContext(ctx context.Context, dest any, returning string, table string, m map[string]any) error {
+	s, values, err := insertSt(x.DbType, table, m)
+	if err != nil {
+		return fmt.Errorf("InsertMapReturning: %w", err)
+	}
 	s += " returning " + returning
-	return x.Get(dest, s, values...)
+	return x.get(ctx, "InsertMapReturning", dest, s, values...)
 }
 
 func (x *DB) UpdateMap(table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
-	s, values := updateSt(x.DbType, table, m, where, where_vals...)
-	res, err := x.Exec(s, values...)
+	return x.UpdateMapContext(x.Ctx, table, m, where, where_vals...)
+}
+
+func (x *DB) UpdateMapContext(ctx context.Context, table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	res, err := x.exec(ctx, "UpdateMap", s, values...)
 
 	return res, err
 }
@@ -98,7 +310,94 @@ func (x *DB) UpdateMap(table string, m map[string]any, where string, where_vals
 // dest must be a pointer to destination
 // returning is the name(s) of the field(s)
 func (x *DB) UpdateMapReturning(dest any, returning string, table string, m map[string]any, where string, where_vals ...any) error {
-	s, values := updateSt(x.DbType, table, m, where, where_vals...)
+	return x.UpdateMapReturningContext(x.Ctx, dest, returning, table, m, where, where_vals...)
+}
+
+func (x *DB) UpdateMapReturningContext(ctx context.Context, dest any, returning string, table string, m map[string]any, where string, where_vals ...any) error {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
 	s += " returning " + returning
-	return x.Get(dest, s, values...)
+	return x.get(ctx, "UpdateMapReturning", dest, s, values...)
+}
+
+// UpdateMapOne is like UpdateMap but return ErrRowsAffected
+// if it didn't update exactly one row
+func (x *DB) UpdateMapOne(table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	return x.UpdateMapExpect(1, table, m, where, where_vals...)
+}
+
+// UpdateMapExpect is like UpdateMap but return ErrRowsAffected
+// if it didn't update n rows
+func (x *DB) UpdateMapExpect(n int64, table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	res, err := x.UpdateMap(table, m, where, where_vals...)
+	return expectRows(n, res, err)
+}
+
+// Delete the rows of table matching where
+func (x *DB) Delete(table string, where string, where_vals ...any) (sql.Result, error) {
+	return x.DeleteContext(x.Ctx, table, where, where_vals...)
+}
+
+func (x *DB) DeleteContext(ctx context.Context, table string, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	s, err := deleteSt(x.DbType, table, where)
+	if err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	return x.exec(ctx, "Delete", s, where_vals...)
+}
+
+// DeleteOne is like Delete but return ErrRowsAffected
+// if it didn't delete exactly one row
+func (x *DB) DeleteOne(table string, where string, where_vals ...any) (sql.Result, error) {
+	return x.DeleteExpect(1, table, where, where_vals...)
+}
+
+// DeleteExpect is like Delete but return ErrRowsAffected
+// if it didn't delete n rows
+func (x *DB) DeleteExpect(n int64, table string, where string, where_vals ...any) (sql.Result, error) {
+	res, err := x.Delete(table, where, where_vals...)
+	return expectRows(n, res, err)
+}
+
+// UpdateMapVersion is UpdateMap with optimistic locking
+// the row must have expected in the column version
+// version is set to version+1, or to m[version] if given (updated_at...)
+// ErrStaleRow if no row matched
+func (x *DB) UpdateMapVersion(table string, m map[string]any, version string, expected any, where string, where_vals ...any) (sql.Result, error) {
+	return x.UpdateMapVersionContext(x.Ctx, table, m, version, expected, where, where_vals...)
+}
+
+func (x *DB) UpdateMapVersionContext(ctx context.Context, table string, m map[string]any, version string, expected any, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	m, where, where_vals, err = versionSt(x.DbType, m, version, expected, where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	return checkStale(x.exec(ctx, "UpdateMapVersion", s, values...))
 }
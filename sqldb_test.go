@@ -0,0 +1,136 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestInTx(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_MSSQL
+
+	err := x.InTx(func(tx *Tx) error {
+		if tx.DbType != DB_MSSQL {
+			t.Errorf("DbType not propagated %d", tx.DbType)
+		}
+		_, err := tx.Exec("insert into t values (1)")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	boom := errors.New("boom")
+	err = x.InTx(func(tx *Tx) error {
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("attend boom reçoit %v", err)
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "panic" {
+				t.Errorf("attend panic reçoit %v", p)
+			}
+		}()
+		x.InTx(func(tx *Tx) error {
+			panic("panic")
+		})
+	}()
+
+	want := "BEGIN insert into t values (1) COMMIT BEGIN ROLLBACK BEGIN ROLLBACK"
+	if q := strings.Join(f.Queries(), " "); q != want {
+		t.Errorf("attend %s reçoit %s", want, q)
+	}
+
+	f.result = func(q string, _ []any) fakeRes {
+		if q == "ROLLBACK" {
+			return fakeRes{err: errors.New("rollback failed")}
+		}
+		return fakeRes{}
+	}
+	err = x.InTx(func(tx *Tx) error {
+		return boom
+	})
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "rollback failed") {
+		t.Errorf("attend boom et rollback failed reçoit %v", err)
+	}
+}
+
+func TestBeginWith(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.Logger = log.New(io.Discard, "", 0)
+	x.Tracer = &recorder{}
+
+	tx, err := x.BeginWith(TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true, Deferrable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Logger != x.Logger || tx.Tracer != x.Tracer {
+		t.Errorf("Logger et Tracer non propagés")
+	}
+	tx.Commit()
+
+	x.DbType = DB_MSSQL
+	if _, err := x.BeginWith(TxOptions{ReadOnly: true}); !errors.Is(err, ErrTxOption) {
+		t.Errorf("read only sqlserver attend ErrTxOption reçoit %v", err)
+	}
+	if _, err := x.BeginWith(TxOptions{Deferrable: true}); !errors.Is(err, ErrTxOption) {
+		t.Errorf("deferrable sqlserver attend ErrTxOption reçoit %v", err)
+	}
+	tx, _ = x.BeginWith(TxOptions{Isolation: sql.LevelSnapshot})
+	if tx.DbType != DB_MSSQL {
+		t.Errorf("DbType non propagé")
+	}
+	tx.Commit()
+
+	want := "BEGIN Serializable READ ONLY SET TRANSACTION DEFERRABLE COMMIT BEGIN Snapshot COMMIT"
+	if q := strings.Join(f.Queries(), " "); q != want {
+		t.Errorf("attend %s\nreçoit %s", want, q)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_MSSQL
+	x.Tracer = &recorder{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := x.ExecContext(ctx, "update t"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecContext attend canceled reçoit %v", err)
+	}
+	xc := x.WithContext(ctx)
+	if xc.Ctx != ctx || xc.DbType != DB_MSSQL || xc.Tracer != x.Tracer || x.Ctx == ctx {
+		t.Errorf("WithContext %+v", xc)
+	}
+	if _, err := xc.InsertMap("t", map[string]any{"a": 1}); !errors.Is(err, context.Canceled) {
+		t.Errorf("InsertMap attend canceled reçoit %v", err)
+	}
+
+	tx, _ := x.Begin()
+	committed := false
+	tx.WithContext(context.Background()).OnCommit(func() { committed = true })
+	if _, err := tx.WithContext(ctx).Exec("update t"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Tx.WithContext attend canceled reçoit %v", err)
+	}
+	tx.Commit()
+	if !committed {
+		t.Errorf("OnCommit d'une copie non appelé")
+	}
+	if q := strings.Join(f.Queries(), " "); q != "BEGIN COMMIT" {
+		t.Errorf("attend BEGIN COMMIT reçoit %s", q)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// env is what a wrapper (DB, Tx, Conn) pass down to run a statement
+type env struct {
+	ctx       context.Context
+	logger    *log.Logger
+	dbType    int
+	tracer    Tracer
+	traceFake bool
+	metrics   *Metrics
+	leaks     *LeakDetector
+	timeout   time.Duration
+	stTimeout bool
+
+	expandSlices bool
+	portable     bool
+	redactArgs   bool
+	safeUpdates  bool
+
+	timeoutInCtx bool // the Timeout of the rows of Query is already in ctx
+
+	q     queryExecer // the DB Tx or Conn of sqlx
+	stmts *StmtCache
+}
+
+// with renvoi l'env avec ctx
+func (e env) with(ctx context.Context) env {
+	e.ctx = ctx
+	return e
+}
+
+func (e env) log(query string, args ...any) {
+	if e.logger == nil || query == "" {
+		return
+	}
+	e.logger.Println(sql_fake(e.dbType, query, args...))
+}
+
+// run log the query and call fn inside the span of op
+// the duration is counted in the metrics
+func (e env) run(op string, query string, args []any, fn func(ctx context.Context) (sql.Result, error)) (sql.Result, error) {
+	e.log(query, args...)
+	ctx, span := startSpan(e.ctx, e.tracer, e.dbType, e.traceFake, op, query, args)
+	ctx, cancel := e.withTimeout(ctx, query)
+	defer cancel()
+	start := time.Now()
+	res, err := fn(ctx)
+	err = wrapDBError(err)
+	if err != nil && isTimeout(ctx, err) {
+		err = e.timeoutErr(query, args, err)
+	}
+	if e.metrics != nil {
+		e.metrics.observe(op, query, time.Since(start), err)
+	}
+	endSpan(span, res, err)
+	return res, err
+}
+
+// stmt rewrite the query (Portable, ExpandSlices) then run it
+// fn receive q, the prepared statement with Stmts, and the query and args rewritten
+// the error is a *QueryError
+func (e env) stmt(op string, query string, args []any, fn func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error)) (sql.Result, error) {
+	s, a, err := e.rewrite(op, query, args)
+	if err != nil {
+		return nil, e.queryErr(op, query, args, err)
+	}
+	res, err := e.run(op, s, a, func(ctx context.Context) (sql.Result, error) {
+		q, release, err := e.prepared(ctx, op, s)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return fn(ctx, q, s, a)
+	})
+	if err != nil {
+		if e.stmts != nil && isConnErr(err) {
+			e.stmts.Clear()
+		}
+		return res, e.queryErr(op, s, a, err)
+	}
+	return res, nil
+}
+
+// TxOptions of BeginWith
+type TxOptions struct {
+	Isolation  sql.IsolationLevel
+	ReadOnly   bool // not with sqlserver, ErrTxOption
+	Deferrable bool // postgres only, for serializable read only, ErrTxOption
+}
+
+// ErrTxOption is returned by BeginWith for an option the database can't apply
+var ErrTxOption = errors.New("transaction option not supported")
+
+// beginTx open the transaction with begin and apply opts
+// postgres has no option for deferrable, it's done with SET TRANSACTION
+func (e env) beginTx(opts TxOptions, begin func(context.Context, *sql.TxOptions) (*sqlx.Tx, error)) (*Tx, error) {
+	if opts.ReadOnly && e.dbType == DB_MSSQL {
+		return nil, fmt.Errorf("conn Begin: %w: read only with sqlserver", ErrTxOption)
+	}
+	if opts.Deferrable && e.dbType != DB_PG {
+		return nil, fmt.Errorf("conn Begin: %w: deferrable is for postgres", ErrTxOption)
+	}
+	sqlOpts := &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	var tx *sqlx.Tx
+	_, err := e.run("Begin", "", nil, func(ctx context.Context) (sql.Result, error) {
+		var err error
+		tx, err = begin(ctx, sqlOpts)
+		return nil, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("conn Begin: %w", err)
+	}
+	x := e.newTx(tx)
+	if e.leaks != nil {
+		x.state.release = e.leaks.track("Tx", e.logger)
+	}
+	if opts.Deferrable {
+		if _, err := x.exec(x.Ctx, "Begin", "SET TRANSACTION DEFERRABLE"); err != nil {
+			x.Rollback()
+			return nil, fmt.Errorf("conn Begin: %w", err)
+		}
+	}
+	if e.stTimeout && e.timeout > 0 && e.dbType == DB_PG {
+		q := fmt.Sprintf("SET LOCAL statement_timeout = %d", e.timeout.Milliseconds())
+		if _, err := x.exec(x.Ctx, "Begin", q); err != nil {
+			x.Rollback()
+			return nil, fmt.Errorf("conn Begin: %w", err)
+		}
+	}
+	return x, nil
+}
+
+// newTx wrap tx with the settings of the env
+func (e env) newTx(tx *sqlx.Tx) *Tx {
+	return &Tx{
+		tx:        tx,
+		Ctx:       e.ctx,
+		Logger:    e.logger,
+		DbType:    e.dbType,
+		Tracer:    e.tracer,
+		TraceFake: e.traceFake,
+		Metrics:   e.metrics,
+		Leaks:     e.leaks,
+		Timeout:   e.timeout,
+
+		StatementTimeout: e.stTimeout,
+		ExpandSlices:     e.expandSlices,
+		Portable:         e.portable,
+		RedactArgs:       e.redactArgs,
+		SafeUpdates:      e.safeUpdates,
+		Stmts:            e.stmts,
+		state:            newTxState(),
+	}
+}
+
+// setConn give the settings of the env to conn and track it for Leaks
+func (e env) setConn(conn *Conn) {
+	conn.Logger = e.logger
+	conn.DbType = e.dbType
+	conn.Tracer = e.tracer
+	conn.TraceFake = e.traceFake
+	conn.Metrics = e.metrics
+	conn.Leaks = e.leaks
+	conn.Timeout = e.timeout
+	conn.StatementTimeout = e.stTimeout
+	conn.ExpandSlices = e.expandSlices
+	conn.Portable = e.portable
+	conn.RedactArgs = e.redactArgs
+	conn.SafeUpdates = e.safeUpdates
+	if e.stmts != nil {
+		conn.CacheStatements(e.stmts.size)
+	}
+	if e.leaks != nil {
+		conn.release = e.leaks.track("Conn", e.logger)
+	}
+}
+
+// inTx run fn with tx, commit if it return nil
+// rollback on error or panic, the panic is raised again after
+func inTx(tx *Tx, fn func(*Tx) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			return fmt.Errorf("%w (%w)", err, rerr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
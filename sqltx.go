@@ -5,56 +5,308 @@ package sqlo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Tx struct {
-	Ctx    context.Context
-	tx     *sqlx.Tx
-	Logger *log.Logger
-	DbType int
+	Ctx       context.Context
+	tx        *sqlx.Tx
+	Logger    *log.Logger
+	DbType    int
+	Tracer    Tracer
+	TraceFake bool // db.statement rendered with sql_fake
+	Metrics   *Metrics
+	Leaks     *LeakDetector
+	Timeout   time.Duration // of each statement, 0 for none
+	// StatementTimeout add SET LOCAL statement_timeout at the begin
+	// of the transactions with postgres, so the server cancel too
+	StatementTimeout bool
+	// ExpandSlices develop a slice argument in as many placeholders
+	// "id in ($1)", []int{1, 2} -> "id in ($1,$2)"
+	ExpandSlices bool
+	// Portable: Select Get Exec Query are written with ? placeholders
+	// rebound to $n or @pN according to DbType, ?? for a literal ?
+	Portable bool
+	// RedactArgs keep the values of the args out of QueryError
+	RedactArgs bool
+	// SafeUpdates refuse UpdateMap and Delete with a where empty
+	// or always true (1=1...), ErrUnsafeWhere
+	SafeUpdates bool
+	// Stmts is the cache of the DB or Conn, rebound with Stmtx
+	Stmts *StmtCache
+
+	parent    *Tx    // nil for the transaction itself
+	savepoint string // name of the savepoint of a nested Tx
+	depth     int
+	state     *txState // shared by the copies of WithContext
 }
 
 func WrapTx(ctx context.Context, tx *sqlx.Tx) *Tx {
 	return &Tx{
-		Ctx: ctx,
-		tx:  tx,
+		Ctx:   ctx,
+		tx:    tx,
+		state: newTxState(),
 	}
 }
+
+func (x *Tx) env() env {
+	return env{
+		ctx:       x.Ctx,
+		logger:    x.Logger,
+		dbType:    x.DbType,
+		tracer:    x.Tracer,
+		traceFake: x.TraceFake,
+		metrics:   x.Metrics,
+		leaks:     x.Leaks,
+		timeout:   x.Timeout,
+		stTimeout: x.StatementTimeout,
+
+		expandSlices: x.ExpandSlices,
+		portable:     x.Portable,
+		redactArgs:   x.RedactArgs,
+		safeUpdates:  x.SafeUpdates,
+
+		q:     x.tx,
+		stmts: x.Stmts,
+	}
+}
+
+// WithTimeout renvoi une copie avec le Timeout d
+func (x *Tx) WithTimeout(d time.Duration) *Tx {
+	c := *x
+	c.Timeout = d
+	return &c
+}
+
+// WithContext renvoi une copie qui utilise ctx
+// with the same logger, DbType, hooks and the same transaction
+func (x *Tx) WithContext(ctx context.Context) *Tx {
+	c := *x
+	c.Ctx = ctx
+	return &c
+}
+
+// Commit run the BeforeCommit callbacks, if one fail the Tx is rolled back
+// then commit and run the OnCommit callbacks
+// a callback which panic after the commit is logged as a *CallbackError
+// with Logger (log.Default() if nil), Commit return nil as it's done
 func (x *Tx) Commit() error {
-	err := x.tx.Commit()
+	if err := x.checkDone(); err != nil {
+		return fmt.Errorf("Tx Commit: %w", err)
+	}
+	if err := x.runBeforeCommit(); err != nil {
+		x.Rollback()
+		return fmt.Errorf("Tx Commit: %w", err)
+	}
+	if x.parent != nil {
+		return x.release()
+	}
+	x.commitChildren()
+	_, err := x.env().run("Commit", "", nil, func(context.Context) (sql.Result, error) {
+		return nil, x.tx.Commit()
+	})
 	if err != nil {
+		x.finish(TxRolledBack)
+		x.runCallbacks(false, x.rollbackCallbacks())
 		return fmt.Errorf("Tx Commit: %w", err)
 	}
+	x.finish(TxCommitted)
+	x.runCallbacks(true, x.state.onCommit)
 	return nil
 }
 
+// Rollback does nothing if the Tx is already committed or rolled back
+// so it can be deferred
 func (x *Tx) Rollback() error {
-	err := x.tx.Rollback()
-	if err != nil {
+	if x.state.state != TxActive {
+		return nil
+	}
+	if x.parent != nil {
+		return x.rollbackTo()
+	}
+	_, err := x.env().run("Rollback", "", nil, func(context.Context) (sql.Result, error) {
+		return nil, x.tx.Rollback()
+	})
+	x.finish(TxRolledBack)
+	// ErrTxDone: already rolled back by database/sql (ctx canceled)
+	if err != nil && !errors.Is(err, sql.ErrTxDone) {
 		return fmt.Errorf("Tx Rollback: %w", err)
 	}
+	return x.runCallbacks(false, x.rollbackCallbacks())
+}
+
+// Depth renvoi le niveau d'imbrication, 0 pour la transaction
+func (x *Tx) Depth() int {
+	return x.depth
+}
+
+// Begin create a savepoint (SAVE TRANSACTION with sqlserver) and return
+// a nested Tx, its Commit release the savepoint and its Rollback
+// roll back to the savepoint
+func (x *Tx) Begin() (*Tx, error) {
+	if err := x.checkDone(); err != nil {
+		return nil, fmt.Errorf("Tx Begin: %w", err)
+	}
+	root := x
+	for root.parent != nil {
+		root = root.parent
+	}
+	root.state.savepoints++ // unique, two nested Tx of the same Tx can be open
+	name := fmt.Sprintf("sqlo_sp%d", root.state.savepoints)
+	var q string
+	switch x.DbType {
+	case DB_ACCESS:
+		return nil, fmt.Errorf("Tx Begin: no savepoint with access")
+	case DB_MSSQL:
+		q = "SAVE TRANSACTION " + name
+	default:
+		q = "SAVEPOINT " + name
+	}
+	if _, err := x.exec(x.Ctx, "Begin", q); err != nil {
+		return nil, fmt.Errorf("Tx Begin: %w", err)
+	}
+	nested := x.env().newTx(x.tx)
+	nested.parent = x
+	nested.savepoint = name
+	nested.depth = x.depth + 1
+	nested.state = newTxState()
+	x.state.children = append(x.state.children, nested)
+	return nested, nil
+}
+
+// InTx run fn in a nested Tx, see Begin
+func (x *Tx) InTx(fn func(*Tx) error) error {
+	tx, err := x.Begin()
+	if err != nil {
+		return err
+	}
+	return inTx(tx, fn)
+}
+
+// release the savepoint of a nested Tx, nothing to do with sqlserver
+// the callbacks wait for the outer transaction
+func (x *Tx) release() error {
+	if x.DbType != DB_MSSQL {
+		if _, err := x.exec(x.Ctx, "Commit", "RELEASE SAVEPOINT "+x.savepoint); err != nil {
+			return fmt.Errorf("Tx Commit: %w", err)
+		}
+	}
+	x.commitChildren()
+	x.finish(TxCommitted)
+	x.parent.state.onCommit = append(x.parent.state.onCommit, x.state.onCommit...)
+	x.parent.state.onRollback = append(x.parent.state.onRollback, x.state.onRollback...)
 	return nil
 }
 
-func (x *Tx) log(query string, args ...any) {
-	if x.Logger == nil {
-		return
+// rollbackTo roll back to the savepoint of a nested Tx
+func (x *Tx) rollbackTo() error {
+	if err := x.checkDone(); err != nil {
+		return fmt.Errorf("Tx Rollback: %w", err)
+	}
+	q := "ROLLBACK TO SAVEPOINT " + x.savepoint
+	if x.DbType == DB_MSSQL {
+		q = "ROLLBACK TRANSACTION " + x.savepoint
+	}
+	_, err := x.exec(x.Ctx, "Rollback", q)
+	x.finish(TxRolledBack)
+	if err != nil {
+		return fmt.Errorf("Tx Rollback: %w", err)
 	}
-	x.Logger.Println(sql_fake(x.DbType, query, args...))
+	return x.runCallbacks(false, x.rollbackCallbacks())
 }
 
 func (x *Tx) Select(dest any, query string, args ...any) error {
-	x.log(query, args...)
-	return sqlx.SelectContext(x.Ctx, x.tx, dest, query, args...)
+	return x.sel(x.Ctx, "Select", dest, query, args...)
+}
+
+func (x *Tx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return x.sel(ctx, "Select", dest, query, args...)
+}
+
+func (x *Tx) sel(ctx context.Context, op string, dest any, query string, args ...any) error {
+	if err := x.use(); err != nil {
+		return err
+	}
+	_, err := x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		return nil, sqlx.SelectContext(ctx, q, dest, query, args...)
+	})
+	return err
 }
 
 func (x *Tx) Get(dest any, query string, args ...any) error {
-	x.log(query, args...)
-	return sqlx.GetContext(x.Ctx, x.tx, dest, query, args...)
+	return x.get(x.Ctx, "Get", dest, query, args...)
+}
+
+func (x *Tx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return x.get(ctx, "Get", dest, query, args...)
+}
+
+func (x *Tx) get(ctx context.Context, op string, dest any, query string, args ...any) error {
+	if err := x.use(); err != nil {
+		return err
+	}
+	_, err := x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		return nil, sqlx.GetContext(ctx, q, dest, query, args...)
+	})
+	return err
+}
+
+// Query renvoi les lignes de la requête, à fermer par l'appelant
+// Timeout is not applied as the rows outlive the call
+// Rows[T] SelectMaps GetMap SelectTable apply it to the reading of the rows
+func (x *Tx) Query(query string, args ...any) (*sqlx.Rows, error) {
+	return x.QueryContext(x.Ctx, query, args...)
+}
+
+func (x *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	if err := x.use(); err != nil {
+		return nil, err
+	}
+	rows, _, err := x.env().with(ctx).queryRows(query, args, false)
+	return rows, err
+}
+
+// queryRows is Query with Timeout, cancel is to call after the rows
+func (x *Tx) queryRows(query string, args ...any) (*sqlx.Rows, context.CancelFunc, error) {
+	if err := x.use(); err != nil {
+		return nil, nil, err
+	}
+	return x.env().queryRows(query, args, true)
+}
+
+func (x *Tx) queryErr(op string, query string, args []any, err error) error {
+	return x.env().queryErr(op, query, args, err)
+}
+
+// SelectMaps renvoi les lignes en map, for columns unknown before
+// the []byte of text columns are converted to string
+func (x *Tx) SelectMaps(query string, args ...any) ([]map[string]any, error) {
+	return selectMaps(x, query, args...)
+}
+
+// GetMap renvoi la première ligne en map, sql.ErrNoRows if none
+func (x *Tx) GetMap(query string, args ...any) (map[string]any, error) {
+	return getMap(x, query, args...)
+}
+
+// SelectTable is like SelectMaps but keep the order and the type of the columns
+func (x *Tx) SelectTable(query string, args ...any) (*Table, error) {
+	return selectTable(x, query, args...)
+}
+
+// Exists tell if table has a row matching w (can be nil)
+func (x *Tx) Exists(table string, w *Where) (bool, error) {
+	return exists(x, table, w)
+}
+
+// Count renvoi le nombre de lignes de table matching w (can be nil)
+func (x *Tx) Count(table string, w *Where) (int64, error) {
+	return count(x, table, w)
 }
 
 func (x *Tx) MustExec(query string, args ...any) sql.Result {
@@ -65,16 +317,62 @@ func (x *Tx) MustExec(query string, args ...any) sql.Result {
 	return res
 }
 func (x *Tx) Exec(query string, args ...any) (sql.Result, error) {
-	x.log(query, args...)
-	if x.tx == nil {
-		return nil, fmt.Errorf("sxc: %T", x.tx)
+	return x.exec(x.Ctx, "Exec", query, args...)
+}
+
+func (x *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return x.exec(ctx, "Exec", query, args...)
+}
+
+func (x *Tx) exec(ctx context.Context, op string, query string, args ...any) (sql.Result, error) {
+	if err := x.use(); err != nil {
+		return nil, err
+	}
+	return x.env().with(ctx).stmt(op, query, args, func(ctx context.Context, q queryExecer, query string, args []any) (sql.Result, error) {
+		if x.tx == nil {
+			return nil, fmt.Errorf("sxc: %T", x.tx)
+		}
+		return q.ExecContext(ctx, query, args...)
+	})
+}
+
+// NamedExec is like Exec with :name taken from arg (struct or map)
+func (x *Tx) NamedExec(query string, arg any) (sql.Result, error) {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("NamedExec: %w", err)
+	}
+	return x.exec(x.Ctx, "NamedExec", s, args...)
+}
+
+// NamedSelect is like Select with :name taken from arg (struct or map)
+func (x *Tx) NamedSelect(dest any, query string, arg any) error {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return fmt.Errorf("NamedSelect: %w", err)
+	}
+	return x.sel(x.Ctx, "NamedSelect", dest, s, args...)
+}
+
+// NamedGet is like Get with :name taken from arg (struct or map)
+func (x *Tx) NamedGet(dest any, query string, arg any) error {
+	s, args, err := namedSt(x.DbType, query, arg)
+	if err != nil {
+		return fmt.Errorf("NamedGet: %w", err)
 	}
-	return x.tx.ExecContext(x.Ctx, query, args...)
+	return x.get(x.Ctx, "NamedGet", dest, s, args...)
 }
 
 func (x *Tx) InsertMap(table string, m map[string]any) (sql.Result, error) {
-	s, values := insertSt(x.DbType, table, m)
-	res, err := x.Exec(s, values...)
+	return x.InsertMapContext(x.Ctx, table, m)
+}
+
+func (x *Tx) InsertMapContext(ctx context.Context, table string, m map[string]any) (sql.Result, error) {
+	s, values, err := insertSt(x.DbType, table, m)
+	if err != nil {
+		return nil, fmt.Errorf("InsertMap: %w", err)
+	}
+	res, err := x.exec(ctx, "InsertMap", s, values...)
 	return res, err
 }
 
@@ -83,14 +381,35 @@ func (x *Tx) InsertMap(table string, m map[string]any) (sql.Result, error) {
 // dest must be a pointer to destination
 // returning is the name(s) of the field(s)
 func (x *Tx) InsertMapReturning(dest any, returning string, table string, m map[string]any) error {
-	s, values := insertSt(x.DbType, table, m)
+	return x.InsertMapReturningContext(x.Ctx, dest, returning, table, m)
+}
+
+func (x *Tx) InsertMapReturningContext(ctx context.Context, dest any, returning string, table string, m map[string]any) error {
+	s, values, err := insertSt(x.DbType, table, m)
+	if err != nil {
+		return fmt.Errorf("InsertMapReturning: %w", err)
+	}
 	s += " returning " + returning
-	return x.Get(dest, s, values...)
+	return x.get(ctx, "InsertMapReturning", dest, s, values...)
 }
 
 func (x *Tx) UpdateMap(table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
-	s, values := updateSt(x.DbType, table, m, where, where_vals...)
-	res, err := x.Exec(s, values...)
+	return x.UpdateMapContext(x.Ctx, table, m, where, where_vals...)
+}
+
+func (x *Tx) UpdateMapContext(ctx context.Context, table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMap: %w", err)
+	}
+	res, err := x.exec(ctx, "UpdateMap", s, values...)
 
 	return res, err
 }
@@ -100,7 +419,94 @@ func (x *Tx) UpdateMap(table string, m map[string]any, where string, where_vals
 // dest must be a pointer to destination
 // returning is the name(s) of the field(s)
 func (x *Tx) UpdateMapReturning(dest any, returning string, table string, m map[string]any, where string, where_vals ...any) error {
-	s, values := updateSt(x.DbType, table, m, where, where_vals...)
+	return x.UpdateMapReturningContext(x.Ctx, dest, returning, table, m, where, where_vals...)
+}
+
+func (x *Tx) UpdateMapReturningContext(ctx context.Context, dest any, returning string, table string, m map[string]any, where string, where_vals ...any) error {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return fmt.Errorf("UpdateMapReturning: %w", err)
+	}
 	s += " returning " + returning
-	return x.Get(dest, s, values...)
+	return x.get(ctx, "UpdateMapReturning", dest, s, values...)
+}
+
+// UpdateMapOne is like UpdateMap but return ErrRowsAffected
+// if it didn't update exactly one row
+func (x *Tx) UpdateMapOne(table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	return x.UpdateMapExpect(1, table, m, where, where_vals...)
+}
+
+// UpdateMapExpect is like UpdateMap but return ErrRowsAffected
+// if it didn't update n rows
+func (x *Tx) UpdateMapExpect(n int64, table string, m map[string]any, where string, where_vals ...any) (sql.Result, error) {
+	res, err := x.UpdateMap(table, m, where, where_vals...)
+	return expectRows(n, res, err)
+}
+
+// Delete the rows of table matching where
+func (x *Tx) Delete(table string, where string, where_vals ...any) (sql.Result, error) {
+	return x.DeleteContext(x.Ctx, table, where, where_vals...)
+}
+
+func (x *Tx) DeleteContext(ctx context.Context, table string, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	s, err := deleteSt(x.DbType, table, where)
+	if err != nil {
+		return nil, fmt.Errorf("Delete: %w", err)
+	}
+	return x.exec(ctx, "Delete", s, where_vals...)
+}
+
+// DeleteOne is like Delete but return ErrRowsAffected
+// if it didn't delete exactly one row
+func (x *Tx) DeleteOne(table string, where string, where_vals ...any) (sql.Result, error) {
+	return x.DeleteExpect(1, table, where, where_vals...)
+}
+
+// DeleteExpect is like Delete but return ErrRowsAffected
+// if it didn't delete n rows
+func (x *Tx) DeleteExpect(n int64, table string, where string, where_vals ...any) (sql.Result, error) {
+	res, err := x.Delete(table, where, where_vals...)
+	return expectRows(n, res, err)
+}
+
+// UpdateMapVersion is UpdateMap with optimistic locking
+// the row must have expected in the column version
+// version is set to version+1, or to m[version] if given (updated_at...)
+// ErrStaleRow if no row matched
+func (x *Tx) UpdateMapVersion(table string, m map[string]any, version string, expected any, where string, where_vals ...any) (sql.Result, error) {
+	return x.UpdateMapVersionContext(x.Ctx, table, m, version, expected, where, where_vals...)
+}
+
+func (x *Tx) UpdateMapVersionContext(ctx context.Context, table string, m map[string]any, version string, expected any, where string, where_vals ...any) (sql.Result, error) {
+	if err := checkWhere(x.SafeUpdates, where); err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	where, where_vals, err := x.env().bindWhere(where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	m, where, where_vals, err = versionSt(x.DbType, m, version, expected, where, where_vals)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	s, values, err := updateSt(x.DbType, table, m, where, where_vals...)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMapVersion: %w", err)
+	}
+	return checkStale(x.exec(ctx, "UpdateMapVersion", s, values...))
 }
@@ -0,0 +1,126 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"fmt"
+	"log"
+)
+
+// CallbackError is returned by Rollback when OnRollback callbacks panic
+// the transaction itself is done
+// after a commit it's only logged, to not mask the successful commit
+// Committed tell if it was committed
+type CallbackError struct {
+	Committed bool
+	Panics    []any
+}
+
+func (e *CallbackError) Error() string {
+	state := "rolled back"
+	if e.Committed {
+		state = "committed"
+	}
+	return fmt.Sprintf("sqlo: %d callback(s) panic after transaction %s: %v", len(e.Panics), state, e.Panics)
+}
+
+// BeforeCommit register fn to run before the commit
+// if fn return an error the transaction is rolled back
+func (x *Tx) BeforeCommit(fn func(*Tx) error) {
+	x.state.beforeCommit = append(x.state.beforeCommit, fn)
+}
+
+// OnCommit register fn to run after the commit
+// on a nested Tx it waits for the commit of the outer transaction
+// a nested Tx still active is committed with the outer one
+func (x *Tx) OnCommit(fn func()) {
+	x.state.onCommit = append(x.state.onCommit, fn)
+}
+
+// OnRollback register fn to run after the rollback
+// on a nested Tx it run after the rollback to the savepoint
+// or after the rollback of the outer transaction
+func (x *Tx) OnRollback(fn func()) {
+	x.state.onRollback = append(x.state.onRollback, fn)
+}
+
+// rollbackCallbacks renvoi the OnRollback callbacks of x followed by
+// the ones of its nested Tx still active, which are rolled back with x
+func (x *Tx) rollbackCallbacks() []func() {
+	fns := x.state.onRollback
+	for _, c := range x.state.children {
+		if c.state.state == TxActive {
+			fns = append(fns, c.rollbackCallbacks()...)
+			c.finish(TxRolledBack)
+		}
+	}
+	x.state.children = nil
+	return fns
+}
+
+// activeChildren renvoi the nested Tx of x still active, recursively
+func (x *Tx) activeChildren() []*Tx {
+	var txs []*Tx
+	for _, c := range x.state.children {
+		if c.state.state == TxActive {
+			txs = append(txs, c)
+			txs = append(txs, c.activeChildren()...)
+		}
+	}
+	return txs
+}
+
+// commitChildren finish the nested Tx still active, committed with x
+// their callbacks are moved to x
+func (x *Tx) commitChildren() {
+	for _, c := range x.activeChildren() {
+		x.state.onCommit = append(x.state.onCommit, c.state.onCommit...)
+		x.state.onRollback = append(x.state.onRollback, c.state.onRollback...)
+		c.finish(TxCommitted)
+	}
+	x.state.children = nil
+}
+
+// runBeforeCommit run the BeforeCommit of x and of its nested Tx still active
+func (x *Tx) runBeforeCommit() error {
+	fns := x.state.beforeCommit
+	x.state.beforeCommit = nil
+	for _, c := range x.activeChildren() {
+		fns = append(fns, c.state.beforeCommit...)
+		c.state.beforeCommit = nil
+	}
+	for _, fn := range fns {
+		if err := fn(x); err != nil {
+			return fmt.Errorf("BeforeCommit: %w", err)
+		}
+	}
+	return nil
+}
+
+// runCallbacks run fns in order, a panic doesn't stop the others
+// the callbacks are run only once
+func (x *Tx) runCallbacks(committed bool, fns []func()) error {
+	x.state.onCommit = nil
+	x.state.onRollback = nil
+	var panics []any
+	for _, fn := range fns {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panics = append(panics, p)
+				}
+			}()
+			fn()
+		}()
+	}
+	if len(panics) == 0 {
+		return nil
+	}
+	err := &CallbackError{Committed: committed, Panics: panics}
+	logger := x.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Println(err)
+	return err
+}
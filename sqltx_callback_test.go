@@ -0,0 +1,176 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestTxCallbacks(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	calls := []string{}
+	add := func(s string) func() { return func() { calls = append(calls, s) } }
+
+	err := x.InTx(func(tx *Tx) error {
+		tx.OnCommit(add("commit1"))
+		tx.OnRollback(add("rollback1"))
+		tx.BeforeCommit(func(*Tx) error { calls = append(calls, "before"); return nil })
+		tx.InTx(func(sp *Tx) error {
+			sp.OnCommit(add("sp commit"))
+			return nil
+		})
+		tx.InTx(func(sp *Tx) error {
+			sp.OnCommit(add("sp2 commit"))
+			sp.OnRollback(add("sp2 rollback"))
+			return errors.New("boom")
+		})
+		tx.OnCommit(add("commit2"))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "sp2 rollback before commit1 sp commit commit2"
+	if s := strings.Join(calls, " "); s != want {
+		t.Errorf("attend %s\nreçoit %s", want, s)
+	}
+
+	calls = nil
+	err = x.InTx(func(tx *Tx) error {
+		tx.OnCommit(add("commit"))
+		tx.OnRollback(add("rollback"))
+		tx.BeforeCommit(func(*Tx) error { return errors.New("refused") })
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "refused") {
+		t.Errorf("attend refused reçoit %v", err)
+	}
+	if s := strings.Join(calls, " "); s != "rollback" {
+		t.Errorf("attend rollback reçoit %s", s)
+	}
+
+	calls = nil
+	buf := &bytes.Buffer{}
+	x.Logger = log.New(buf, "", 0)
+	err = x.InTx(func(tx *Tx) error {
+		tx.OnCommit(func() { panic("mail") })
+		tx.OnCommit(add("commit"))
+		return nil
+	})
+	if err != nil {
+		t.Errorf("le commit est fait, attend nil reçoit %v", err)
+	}
+	if !strings.Contains(buf.String(), "1 callback(s) panic after transaction committed: [mail]") {
+		t.Errorf("panic non loggé %q", buf.String())
+	}
+	if s := strings.Join(calls, " "); s != "commit" {
+		t.Errorf("attend commit reçoit %s", s)
+	}
+	if q := f.Queries(); q[len(q)-1] != "COMMIT" {
+		t.Errorf("attend COMMIT reçoit %v", q)
+	}
+}
+
+func TestTxCallbacksNestedActive(t *testing.T) {
+	db, _ := openFake(t)
+	x := WrapDB(context.Background(), db)
+	calls := []string{}
+	add := func(s string) func() { return func() { calls = append(calls, s) } }
+
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.OnRollback(add("tx"))
+	sp, err := tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.OnRollback(add("sp"))
+	sp2, err := sp.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp2.OnRollback(add("sp2"))
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(calls, " "); s != "tx sp sp2" {
+		t.Errorf("attend tx sp sp2 reçoit %s", s)
+	}
+	if sp.State() != TxRolledBack || sp2.State() != TxRolledBack {
+		t.Errorf("Tx imbriquées %v %v", sp.State(), sp2.State())
+	}
+	if err := sp.Rollback(); err != nil || len(calls) != 3 {
+		t.Errorf("callbacks lancés deux fois %v %v", calls, err)
+	}
+}
+
+func TestTxCallbacksNestedCommit(t *testing.T) {
+	db, _ := openFake(t)
+	x := WrapDB(context.Background(), db)
+	calls := []string{}
+	add := func(s string) func() { return func() { calls = append(calls, s) } }
+
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.OnCommit(add("tx"))
+	sp, err := tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.OnCommit(add("sp"))
+	sp.BeforeCommit(func(*Tx) error { calls = append(calls, "sp before"); return nil })
+	sp2, err := sp.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp2.OnCommit(add("sp2"))
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(calls, " "); s != "sp before tx sp sp2" {
+		t.Errorf("attend sp before tx sp sp2 reçoit %s", s)
+	}
+	if sp.State() != TxCommitted || sp2.State() != TxCommitted {
+		t.Errorf("Tx imbriquées %v %v", sp.State(), sp2.State())
+	}
+}
+
+func TestTxRollbackTxDone(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	tx.OnRollback(func() { n++ })
+	sp, err := tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.OnRollback(func() { n++ })
+	f.result = func(q string, _ []any) fakeRes {
+		if q == "ROLLBACK" {
+			return fakeRes{err: sql.ErrTxDone}
+		}
+		return fakeRes{}
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Errorf("ErrTxDone est un rollback: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("attend 2 OnRollback reçoit %d", n)
+	}
+}
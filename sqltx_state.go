@@ -0,0 +1,134 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+package sqlo
+
+import (
+	"database/sql"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type TxState int
+
+const (
+	TxActive TxState = iota
+	TxCommitted
+	TxRolledBack
+)
+
+func (s TxState) String() string {
+	switch s {
+	case TxActive:
+		return "active"
+	case TxCommitted:
+		return "committed"
+	case TxRolledBack:
+		return "rolled back"
+	}
+	return fmt.Sprintf("TxState(%d)", int(s))
+}
+
+// TxDoneError is returned by a statement on a Tx already committed
+// or rolled back, At is where it was done
+// it unwrap to sql.ErrTxDone
+type TxDoneError struct {
+	State TxState
+	At    string // file:line of Commit or Rollback
+	Depth int    // of the finished Tx, less than the Tx used if it's an outer one
+}
+
+func (e *TxDoneError) Error() string {
+	return fmt.Sprintf("sqlo: transaction at depth %d %s at %s", e.Depth, e.State, e.At)
+}
+
+func (e *TxDoneError) Unwrap() error {
+	return sql.ErrTxDone
+}
+
+// txState is shared by the copies of a Tx
+type txState struct {
+	state      TxState
+	started    time.Time
+	statements int
+	at         string
+	release    func() // for Leaks
+
+	beforeCommit []func(*Tx) error
+	onCommit     []func()
+	onRollback   []func()
+	children     []*Tx // nested Tx, rolled back with this one
+	savepoints   int   // counter of the names of the savepoints, in the root Tx
+}
+
+func newTxState() *txState {
+	return &txState{started: time.Now()}
+}
+
+// State renvoi active, committed ou rolled back
+func (x *Tx) State() TxState {
+	return x.state.state
+}
+
+// Started renvoi l'heure du Begin
+func (x *Tx) Started() time.Time {
+	return x.state.started
+}
+
+// Statements renvoi le nombre de requêtes de la Tx et des Tx imbriquées
+func (x *Tx) Statements() int {
+	return x.state.statements
+}
+
+func (x *Tx) finish(state TxState) {
+	x.state.state = state
+	x.state.at = caller()
+	if x.state.release != nil {
+		x.state.release()
+	}
+}
+
+// checkDone renvoi une *TxDoneError si la Tx ou une Tx englobante est terminée
+func (x *Tx) checkDone() error {
+	for t := x; t != nil; t = t.parent {
+		if t.state.state != TxActive {
+			return &TxDoneError{State: t.state.state, At: t.state.at, Depth: t.depth}
+		}
+	}
+	return nil
+}
+
+// use check the Tx before a statement and count it
+func (x *Tx) use() error {
+	if err := x.checkDone(); err != nil {
+		return err
+	}
+	for t := x; t != nil; t = t.parent {
+		t.state.statements++
+	}
+	return nil
+}
+
+var pkgPrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	i := strings.LastIndex(name, "/")
+	return name[:i+strings.Index(name[i:], ".")+1]
+}()
+
+// caller renvoi file:line du premier appel hors du package
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) || strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2025 William Dode
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sqlo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSavepoint(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.InTx(func(sp *Tx) error {
+		if sp.Depth() != 1 {
+			t.Errorf("depth attend 1 reçoit %d", sp.Depth())
+		}
+		return sp.InTx(func(sp2 *Tx) error {
+			return errors.New("boom")
+		})
+	})
+	if err == nil {
+		t.Errorf("attend boom")
+	}
+	sp, _ := tx.Begin()
+	sp.Commit()
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN " +
+		"SAVEPOINT sqlo_sp1 SAVEPOINT sqlo_sp2 ROLLBACK TO SAVEPOINT sqlo_sp2 ROLLBACK TO SAVEPOINT sqlo_sp1 " +
+		"SAVEPOINT sqlo_sp3 RELEASE SAVEPOINT sqlo_sp3 COMMIT"
+	if q := strings.Join(f.Queries(), " "); q != want {
+		t.Errorf("attend %s\nreçoit %s", want, q)
+	}
+
+	if _, err := tx.Begin(); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("attend ErrTxDone reçoit %v", err)
+	}
+	if err := sp.Rollback(); err != nil {
+		t.Errorf("Rollback après Commit attend nil reçoit %v", err)
+	}
+	if _, err := sp.Exec("select 1"); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("attend ErrTxDone reçoit %v", err)
+	}
+}
+
+func TestSavepointMssql(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	x.DbType = DB_MSSQL
+	x.InTx(func(tx *Tx) error {
+		tx.InTx(func(*Tx) error { return nil })
+		tx.InTx(func(*Tx) error { return errors.New("boom") })
+		return nil
+	})
+	want := "BEGIN SAVE TRANSACTION sqlo_sp1 SAVE TRANSACTION sqlo_sp2 ROLLBACK TRANSACTION sqlo_sp2 COMMIT"
+	if q := strings.Join(f.Queries(), " "); q != want {
+		t.Errorf("attend %s\nreçoit %s", want, q)
+	}
+
+	x.DbType = DB_ACCESS
+	tx, _ := x.Begin()
+	if _, err := tx.Begin(); err == nil {
+		t.Errorf("access: attend une erreur")
+	}
+	tx.Rollback()
+}
+
+func TestTxState(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+
+	tx, _ := x.Begin()
+	if tx.State() != TxActive || tx.Started().IsZero() {
+		t.Errorf("state %s started %v", tx.State(), tx.Started())
+	}
+	tx.Exec("update t")
+	tx.InTx(func(sp *Tx) error {
+		_, err := sp.Exec("update t")
+		return err
+	})
+	if n := tx.Statements(); n != 4 { // SAVEPOINT et RELEASE
+		t.Errorf("statements attend 4 reçoit %d", n)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Errorf("Rollback après Commit attend nil reçoit %v", err)
+	}
+	n := len(f.Queries())
+	_, err := tx.Exec("update t")
+	var doneErr *TxDoneError
+	if !errors.As(err, &doneErr) || doneErr.State != TxCommitted || !strings.Contains(doneErr.At, "sqltx_test.go") {
+		t.Errorf("attend TxDoneError reçoit %v", err)
+	}
+	if len(f.Queries()) != n {
+		t.Errorf("la requête ne doit pas être envoyée")
+	}
+	if err := tx.Commit(); !errors.As(err, &doneErr) {
+		t.Errorf("Commit après Commit attend TxDoneError reçoit %v", err)
+	}
+}
+
+func TestSavepointSiblings(t *testing.T) {
+	db, f := openFake(t)
+	x := WrapDB(context.Background(), db)
+	tx, err := x.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	sp1, _ := tx.Begin()
+	sp2, _ := tx.Begin()
+	sp1.Rollback()
+	sp2.Rollback()
+	want := "BEGIN SAVEPOINT sqlo_sp1 SAVEPOINT sqlo_sp2 ROLLBACK TO SAVEPOINT sqlo_sp1 ROLLBACK TO SAVEPOINT sqlo_sp2"
+	if q := strings.Join(f.Queries(), " "); q != want {
+		t.Errorf("attend %s\nreçoit %s", want, q)
+	}
+}
@@ -10,7 +10,8 @@ type Raw string
 
 // renvoi la chaine sql et les valeurs pour un insert
 // à partir d'un map
-func insertSt(dbType int, table string, m map[string]any) (string, []any) {
+// table and the keys are validated and quoted with QuoteIdent
+func insertSt(dbType int, table string, m map[string]any) (string, []any, error) {
 	fieldols := make([]string, 0)
 	values := make([]any, 0)
 	fieldnames := make([]string, 0)
@@ -18,6 +19,10 @@ func insertSt(dbType int, table string, m map[string]any) (string, []any) {
 		fieldnames = append(fieldnames, name)
 	}
 	sort.Strings(fieldnames)
+	table, columns, err := quoteIdents(dbType, table, fieldnames)
+	if err != nil {
+		return "", nil, err
+	}
 
 	i := 0
 	for _, name := range fieldnames {
@@ -38,14 +43,15 @@ func insertSt(dbType int, table string, m map[string]any) (string, []any) {
 	}
 	s := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
 		table,
-		strings.Join(fieldnames, ", "),
+		strings.Join(columns, ", "),
 		strings.Join(fieldols, ", "))
-	return s, values
+	return s, values, nil
 }
 
 // renvoi la chaine sql et les valeurs pour un update
 // à partir d'un map
-func updateSt(dbType int, table string, m map[string]any, where string, where_vals ...any) (string, []any) {
+// table and the keys are validated and quoted with QuoteIdent
+func updateSt(dbType int, table string, m map[string]any, where string, where_vals ...any) (string, []any, error) {
 	sets := make([]string, 0)
 	num := len(where_vals) + 1
 	values := []any{}
@@ -58,18 +64,23 @@ func updateSt(dbType int, table string, m map[string]any, where string, where_va
 		fieldnames = append(fieldnames, name)
 	}
 	sort.Strings(fieldnames)
-	for _, name := range fieldnames {
+	table, columns, err := quoteIdents(dbType, table, fieldnames)
+	if err != nil {
+		return "", nil, err
+	}
+	for i, name := range fieldnames {
+		col := columns[i]
 		if _, ok := m[name].(Raw); ok {
-			sets = append(sets, fmt.Sprintf("%s=%s", name, m[name]))
+			sets = append(sets, fmt.Sprintf("%s=%s", col, m[name]))
 			continue
 		}
 		switch dbType {
 		case DB_ACCESS:
-			sets = append(sets, fmt.Sprintf("%s=?", name))
+			sets = append(sets, fmt.Sprintf("%s=?", col))
 		case DB_MSSQL:
-			sets = append(sets, fmt.Sprintf("%s=@p%d", name, num))
+			sets = append(sets, fmt.Sprintf("%s=@p%d", col, num))
 		default:
-			sets = append(sets, fmt.Sprintf("%s=$%d", name, num))
+			sets = append(sets, fmt.Sprintf("%s=$%d", col, num))
 		}
 		num += 1
 		values = append(values, m[name])
@@ -82,5 +93,30 @@ func updateSt(dbType int, table string, m map[string]any, where string, where_va
 	if dbType == DB_ACCESS {
 		values = append(values, where_vals...)
 	}
-	return s, values
+	return s, values, nil
+}
+
+// quoteIdents renvoi table et columns quoted with QuoteIdent
+func quoteIdents(dbType int, table string, columns []string) (string, []string, error) {
+	table, err := QuoteIdent(dbType, table)
+	if err != nil {
+		return "", nil, err
+	}
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		if quoted[i], err = QuoteIdent(dbType, c); err != nil {
+			return "", nil, err
+		}
+	}
+	return table, quoted, nil
+}
+
+// renvoi la chaine sql d'un delete
+// table is validated and quoted with QuoteIdent
+func deleteSt(dbType int, table string, where string) (string, error) {
+	table, err := QuoteIdent(dbType, table)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), nil
 }